@@ -1 +1,116 @@
-package server
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/handler"
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/server"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/persistence"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/pubsub"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/storage"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:6379", "TCP listen address (empty to disable)")
+	unixSocket := flag.String("unixsocket", "", "Unix domain socket path")
+	maxInlineLen := flag.Int("proto-max-inline-len", 64*1024, "maximum length in bytes of an inline request")
+	tlsCertFile := flag.String("tls-cert-file", "", "TLS certificate file")
+	tlsKeyFile := flag.String("tls-key-file", "", "TLS private key file")
+	tlsCACertFile := flag.String("tls-ca-cert-file", "", "CA certificate used to verify clients")
+	tlsAuthClients := flag.Bool("tls-auth-clients", false, "require and verify client certificates")
+	enableAOF := flag.Bool("appendonly", false, "enable the append-only file")
+	dumpPath := flag.String("dbfilename", "dump.ndjson", "file written by BGSAVE")
+	aofPath := flag.String("appendfilename", "appendonly.aof", "append-only file path")
+	maxMemory := flag.Int64("maxmemory", 0, "memory limit in bytes for the noeviction policy (0 = unlimited)")
+	keysLimit := flag.Int("keys-limit", 0, "maximum keys a single KEYS call may return (0 = unlimited)")
+	defaultTTL := flag.Int64("default-ttl-seconds", 0, "TTL applied to keys set without an expiry (0 = none)")
+	maxBulkLen := flag.Int("proto-max-bulk-len", 512*1024*1024, "maximum size in bytes of a single value")
+	compressThreshold := flag.Int("compress-threshold", 0, "store values of at least this many bytes deflated (0 disables)")
+	copyOnWrite := flag.Bool("copy-on-write-reads", false, "serve reads from lock-free snapshots rebuilt on every write")
+	notifyKeyspaceEvents := flag.String("notify-keyspace-events", "", "keyspace notification classes, e.g. KEA")
+	cleanupInterval := flag.Int64("cleanup-interval-ms", 100, "active expiry interval in milliseconds")
+	enableDebug := flag.Bool("enable-debug-command", false, "allow the DEBUG command")
+	slowlogSlowerThan := flag.Int64("slowlog-log-slower-than", 10000, "slow log threshold in microseconds (negative disables)")
+	slowlogMaxLen := flag.Int("slowlog-max-len", 128, "maximum slow log entries")
+	outputBufferSize := flag.Int("output-buffer-size", 16*1024, "bytes of pipelined replies buffered before flushing")
+	commandTimeout := flag.Duration("command-timeout", 0, "cancel commands running longer than this (0 disables)")
+	logCommands := flag.Bool("log-commands", false, "log every command with its client address")
+	logRedact := flag.String("log-redact-commands", "", "comma-separated commands, optionally with a subcommand such as \"CONFIG SET\", whose arguments are masked in the command log")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	store := storage.NewStore(storage.StoreOption{
+		KeysLimit:         *keysLimit,
+		MaxMemory:         *maxMemory,
+		MaxValueSize:      *maxBulkLen,
+		CopyOnWrite:       *copyOnWrite,
+		CompressThreshold: *compressThreshold,
+	})
+	aof, err := persistence.NewAOFProvider(persistence.AOFProviderOption{
+		EnableAOF: *enableAOF,
+		Filepath:  *aofPath,
+	})
+	if err != nil {
+		log.Fatalf("failed to open AOF: %v", err)
+	}
+	if aof != nil {
+		defer aof.Close()
+		if err := aof.Replay(ctx, store); err != nil {
+			log.Fatalf("failed to replay AOF: %v", err)
+		}
+	}
+	broker := pubsub.NewBroker()
+	notifier, err := pubsub.NewKeyspaceNotifier(broker, pubsub.KeyspaceNotifierOption{
+		NotifyKeyspaceEvents: *notifyKeyspaceEvents,
+	})
+	if err != nil {
+		log.Fatalf("invalid notify-keyspace-events: %v", err)
+	}
+	notifier.Attach(store)
+	store.StartCleanup(*cleanupInterval)
+	defer store.StopCleanup()
+
+	dispatcher := handler.NewDispatcher(store, aof, broker, handler.DispatcherOption{
+		EnableDebugCommand:   *enableDebug,
+		SlowlogLogSlowerThan: *slowlogSlowerThan,
+		SlowlogMaxLen:        *slowlogMaxLen,
+		CommandTimeout:       *commandTimeout,
+		DefaultTTLSeconds:    *defaultTTL,
+		Reload: func(ctx context.Context) error {
+			return persistence.Reload(ctx, store)
+		},
+		Save: func(ctx context.Context) error {
+			return persistence.SaveFile(ctx, store, *dumpPath)
+		},
+	})
+	defer dispatcher.Wait()
+	if *logCommands {
+		var redact []string
+		if *logRedact != "" {
+			redact = strings.Split(*logRedact, ",")
+		}
+		dispatcher.Use(handler.Logging(log.Default(), handler.LoggingOption{RedactCommands: redact}))
+	}
+	srv := server.NewServer(dispatcher, broker, server.ServerOption{
+		Addr:             *addr,
+		UnixSocket:       *unixSocket,
+		TLSCertFile:      *tlsCertFile,
+		TLSKeyFile:       *tlsKeyFile,
+		TLSCACertFile:    *tlsCACertFile,
+		TLSAuthClients:   *tlsAuthClients,
+		MaxInlineLen:     *maxInlineLen,
+		MaxBulkLen:       *maxBulkLen,
+		OutputBufferSize: *outputBufferSize,
+	})
+	if err := srv.ListenAndServe(ctx); err != nil {
+		log.Printf("server error: %v", err)
+	}
+}
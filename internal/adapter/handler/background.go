@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"sync"
+)
+
+var errBackgroundBusy = errors.New("background job already in progress")
+
+// backgroundQueue runs at most one heavy job at a time, mirroring Redis's
+// single child process for BGSAVE and AOF rewrites.
+type backgroundQueue struct {
+	mu      sync.Mutex
+	running string
+	wg      sync.WaitGroup
+}
+
+func (q *backgroundQueue) submit(name string, job func()) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running != "" {
+		return errBackgroundBusy
+	}
+	q.running = name
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		job()
+		q.mu.Lock()
+		q.running = ""
+		q.mu.Unlock()
+	}()
+	return nil
+}
+
+func (q *backgroundQueue) current() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running
+}
+
+func (q *backgroundQueue) wait() {
+	q.wg.Wait()
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+)
+
+// histogramBuckets covers latencies up to 2^31 microseconds; bucket i counts
+// calls that took less than 2^i microseconds.
+const histogramBuckets = 32
+
+type commandStat struct {
+	calls     atomic.Int64
+	failed    atomic.Int64
+	usec      atomic.Int64
+	histogram [histogramBuckets]atomic.Int64
+}
+
+type CommandStats struct {
+	stats  sync.Map
+	errors sync.Map
+}
+
+func NewCommandStats() *CommandStats {
+	return &CommandStats{}
+}
+
+func (c *CommandStats) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, cmd *protocol.Command) any {
+			start := time.Now()
+			result := next(ctx, cmd)
+			err, failed := result.(error)
+			c.record(cmd.Type, time.Since(start), failed)
+			if failed {
+				c.RecordError(err)
+			}
+			return result
+		}
+	}
+}
+
+// Histogram returns the per-bucket call counts for cmdType, keyed by the
+// bucket's exclusive upper bound in microseconds. Empty buckets are omitted.
+func (c *CommandStats) Histogram(cmdType command.Type) map[int64]int64 {
+	histogram := make(map[int64]int64)
+	value, exists := c.stats.Load(cmdType)
+	if !exists {
+		return histogram
+	}
+	stat := value.(*commandStat)
+	for i := range stat.histogram {
+		if n := stat.histogram[i].Load(); n > 0 {
+			histogram[int64(1)<<i] = n
+		}
+	}
+	return histogram
+}
+
+// RecordError counts err under its reply prefix for INFO errorstats.
+func (c *CommandStats) RecordError(err error) {
+	value, _ := c.errors.LoadOrStore(protocol.ErrorPrefix(err), &atomic.Int64{})
+	value.(*atomic.Int64).Add(1)
+}
+
+func (c *CommandStats) Reset() {
+	c.stats.Clear()
+	c.errors.Clear()
+}
+
+func (c *CommandStats) ErrorInfo() string {
+	var prefixes []string
+	c.errors.Range(func(key, value any) bool {
+		prefixes = append(prefixes, key.(string))
+		return true
+	})
+	sort.Strings(prefixes)
+	var sb strings.Builder
+	sb.WriteString("# Errorstats")
+	for _, prefix := range prefixes {
+		value, _ := c.errors.Load(prefix)
+		fmt.Fprintf(&sb, "\r\nerrorstat_%s:count=%d", prefix, value.(*atomic.Int64).Load())
+	}
+	return sb.String()
+}
+
+func (c *CommandStats) Info() string {
+	var names []string
+	lines := make(map[string]string)
+	c.stats.Range(func(key, value any) bool {
+		name := strings.ToLower(key.(command.Type).String())
+		stat := value.(*commandStat)
+		calls, usec := stat.calls.Load(), stat.usec.Load()
+		perCall := 0.0
+		if calls > 0 {
+			perCall = float64(usec) / float64(calls)
+		}
+		names = append(names, name)
+		lines[name] = fmt.Sprintf("cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=0,failed_calls=%d",
+			name, calls, usec, perCall, stat.failed.Load())
+		return true
+	})
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("# Commandstats")
+	for _, name := range names {
+		sb.WriteString("\r\n")
+		sb.WriteString(lines[name])
+	}
+	return sb.String()
+}
+
+func (c *CommandStats) record(cmdType command.Type, duration time.Duration, failed bool) {
+	value, _ := c.stats.LoadOrStore(cmdType, &commandStat{})
+	stat := value.(*commandStat)
+	usec := duration.Microseconds()
+	stat.calls.Add(1)
+	stat.usec.Add(usec)
+	if failed {
+		stat.failed.Add(1)
+	}
+	stat.histogram[min(bits.Len64(uint64(usec)), histogramBuckets-1)].Add(1)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+)
+
+// cmdstat parses the fields of the cmdstat line for name out of info.
+func cmdstat(t *testing.T, info, name string) map[string]string {
+	t.Helper()
+	for _, line := range strings.Split(info, "\r\n") {
+		if rest, found := strings.CutPrefix(line, "cmdstat_"+name+":"); found {
+			fields := make(map[string]string)
+			for _, pair := range strings.Split(rest, ",") {
+				key, value, _ := strings.Cut(pair, "=")
+				fields[key] = value
+			}
+			return fields
+		}
+	}
+	t.Fatalf("no cmdstat_%s in %q", name, info)
+	return nil
+}
+
+func TestCommandStatsCountsCalls(t *testing.T) {
+	stats := NewCommandStats()
+	handle := stats.Interceptor()(func(ctx context.Context, cmd *protocol.Command) any {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		handle(context.Background(), &protocol.Command{Type: command.GET})
+	}
+	fields := cmdstat(t, stats.Info(), "get")
+	if fields["calls"] != "3" {
+		t.Errorf("calls = %s, want 3", fields["calls"])
+	}
+	usec, _ := strconv.Atoi(fields["usec"])
+	if usec < 3000 {
+		t.Errorf("usec = %d for three 1ms calls, want at least 3000", usec)
+	}
+	if fields["failed_calls"] != "0" {
+		t.Errorf("failed_calls = %s, want 0", fields["failed_calls"])
+	}
+	var histogramCalls int64
+	for _, n := range stats.Histogram(command.GET) {
+		histogramCalls += n
+	}
+	if histogramCalls != 3 {
+		t.Errorf("histogram holds %d calls, want 3", histogramCalls)
+	}
+	stats.Reset()
+	if info := stats.Info(); info != "# Commandstats" {
+		t.Errorf("Info after Reset = %q", info)
+	}
+}
+
+func TestCommandStatsInInfo(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	dispatch(t, d, "SET", "k", "v")
+	for i := 0; i < 4; i++ {
+		dispatch(t, d, "GET", "k")
+	}
+	info, _ := dispatch(t, d, "INFO").(protocol.BulkString)
+	if calls := cmdstat(t, string(info), "get")["calls"]; calls != "4" {
+		t.Errorf("cmdstat_get calls = %s, want 4", calls)
+	}
+	if calls := cmdstat(t, string(info), "set")["calls"]; calls != "1" {
+		t.Errorf("cmdstat_set calls = %s, want 1", calls)
+	}
+}
+
+func TestErrorStats(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	dispatch(t, d, "SET", "k", "v")
+	dispatch(t, d, "PFADD", "k", "x")
+	dispatch(t, d, "PFCOUNT", "k")
+	dispatch(t, d, "SET", "k", "v", "BOGUS")
+	if field := infoField(t, d, "errorstat_WRONGTYPE"); field != "count=2" {
+		t.Errorf("errorstat_WRONGTYPE = %q, want count=2", field)
+	}
+	if field := infoField(t, d, "errorstat_ERR"); field != "count=1" {
+		t.Errorf("errorstat_ERR = %q, want count=1", field)
+	}
+	dispatch(t, d, "CONFIG", "RESETSTAT")
+	if field := infoField(t, d, "errorstat_WRONGTYPE"); field != "" {
+		t.Errorf("errorstat_WRONGTYPE = %q after CONFIG RESETSTAT, want it cleared", field)
+	}
+}
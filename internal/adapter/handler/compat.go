@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+)
+
+// Compatibility shims for admin commands that tools probe at startup. This
+// server has no replicas and no latency monitor, so they report an empty
+// state instead of failing with an unknown command error.
+
+func (d *Dispatcher) failover(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 1 && strings.EqualFold(cmd.Args[0], "ABORT") {
+		return fmt.Errorf("No failover in progress.")
+	}
+	return fmt.Errorf("FAILOVER requires connected replicas.")
+}
+
+func (d *Dispatcher) latency(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "RESET":
+		return 0
+	case "LATEST":
+		return protocol.Array{}
+	case "HISTORY":
+		if len(cmd.Args) != 2 {
+			return wrongArgs(cmd)
+		}
+		return protocol.Array{}
+	case "DOCTOR":
+		return protocol.BulkString("I have no latency reports to show you at this time.")
+	case "HISTOGRAM":
+		return d.latencyHistogram(cmd.Args[1:])
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+// latencyHistogram reports the commandstats latency buckets in the layout
+// of Redis's LATENCY HISTOGRAM, with cumulative counts per bucket.
+func (d *Dispatcher) latencyHistogram(names []string) protocol.Array {
+	var types []command.Type
+	if len(names) == 0 {
+		for cmdType := range d.handlers {
+			types = append(types, cmdType)
+		}
+	} else {
+		for _, name := range names {
+			if cmdType := command.Type(strings.ToUpper(name)); cmdType.IsValid() {
+				types = append(types, cmdType)
+			}
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	reply := protocol.Array{}
+	for _, cmdType := range types {
+		histogram := d.stats.Histogram(cmdType)
+		if len(histogram) == 0 {
+			continue
+		}
+		bounds := make([]int64, 0, len(histogram))
+		for bound := range histogram {
+			bounds = append(bounds, bound)
+		}
+		sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+		buckets := make(protocol.Array, 0, len(bounds)*2)
+		var calls int64
+		for _, bound := range bounds {
+			calls += histogram[bound]
+			buckets = append(buckets, bound, calls)
+		}
+		reply = append(reply, protocol.BulkString(strings.ToLower(cmdType.String())),
+			protocol.Array{protocol.BulkString("calls"), calls, protocol.BulkString("histogram_usec"), buckets})
+	}
+	return reply
+}
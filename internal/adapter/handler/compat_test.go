@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+)
+
+func TestCompatibilityShims(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"FAILOVER", "ABORT"}, "-ERR No failover in progress.\r\n"},
+		{[]string{"FAILOVER"}, "-ERR FAILOVER requires connected replicas.\r\n"},
+		{[]string{"LATENCY", "RESET"}, ":0\r\n"},
+		{[]string{"LATENCY", "RESET", "command"}, ":0\r\n"},
+		{[]string{"LATENCY", "LATEST"}, "*0\r\n"},
+		{[]string{"LATENCY", "HISTORY", "command"}, "*0\r\n"},
+		{[]string{"LATENCY", "DOCTOR"}, "$51\r\nI have no latency reports to show you at this time.\r\n"},
+	}
+	for _, tt := range tests {
+		if got := dispatchRESP(t, d, tt.args...); got != tt.want {
+			t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	if reply := dispatch(t, d, "LATENCY", "HISTOGRAM", "get"); len(reply.(protocol.Array)) != 0 {
+		t.Errorf("LATENCY HISTOGRAM before any GET = %v, want empty", reply)
+	}
+	for i := 0; i < 3; i++ {
+		dispatch(t, d, "GET", "k")
+	}
+	reply, _ := dispatch(t, d, "LATENCY", "HISTOGRAM", "get", "set").(protocol.Array)
+	if len(reply) != 2 || reply[0] != protocol.BulkString("get") {
+		t.Fatalf("LATENCY HISTOGRAM get set = %v, want only get", reply)
+	}
+	detail, _ := reply[1].(protocol.Array)
+	if len(detail) != 4 || detail[1] != int64(3) {
+		t.Fatalf("get histogram = %v, want 3 calls", detail)
+	}
+	buckets, _ := detail[3].(protocol.Array)
+	if last := buckets[len(buckets)-1]; last != int64(3) {
+		t.Errorf("last cumulative bucket = %v, want 3", last)
+	}
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+)
+
+type configParam struct {
+	get func(ctx context.Context) string
+	set func(ctx context.Context, value string) error
+}
+
+func (d *Dispatcher) configParams() map[string]configParam {
+	return map[string]configParam{
+		// Kept for clients that set it; flushes never free on the request
+		// path whatever its value.
+		"lazyfree-lazy-user-flush": {
+			get: func(ctx context.Context) string {
+				return yesNo(d.lazyFlush.Load())
+			},
+			set: func(ctx context.Context, value string) error {
+				enabled, err := parseYesNo(value)
+				if err != nil {
+					return err
+				}
+				d.lazyFlush.Store(enabled)
+				return nil
+			},
+		},
+		"maxmemory": {
+			get: func(ctx context.Context) string {
+				return strconv.FormatInt(d.store.MaxMemory(), 10)
+			},
+			set: func(ctx context.Context, value string) error {
+				bytes, err := parseMemory(value)
+				if err != nil {
+					return err
+				}
+				d.store.SetMaxMemory(bytes)
+				return nil
+			},
+		},
+		"maxmemory-policy": {
+			get: func(ctx context.Context) string {
+				return "noeviction"
+			},
+			set: func(ctx context.Context, value string) error {
+				if !strings.EqualFold(value, "noeviction") {
+					return fmt.Errorf("only the noeviction policy is supported")
+				}
+				return nil
+			},
+		},
+		"keys-limit": {
+			get: func(ctx context.Context) string {
+				return strconv.Itoa(d.store.KeysLimit())
+			},
+			set: func(ctx context.Context, value string) error {
+				limit, err := strconv.Atoi(value)
+				if err != nil || limit < 0 {
+					return fmt.Errorf("argument must be a non-negative integer")
+				}
+				d.store.SetKeysLimit(limit)
+				return nil
+			},
+		},
+		"cleanup-interval-ms": {
+			get: func(ctx context.Context) string {
+				return strconv.FormatInt(d.store.CleanupInterval(), 10)
+			},
+			set: func(ctx context.Context, value string) error {
+				ms, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || ms < 0 {
+					return fmt.Errorf("argument must be a non-negative integer")
+				}
+				d.store.SetCleanupInterval(ms)
+				return nil
+			},
+		},
+	}
+}
+
+var memoryUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10},
+	{"g", 1000 * 1000 * 1000}, {"m", 1000 * 1000}, {"k", 1000}, {"b", 1},
+}
+
+// parseMemory parses a byte count with Redis's optional unit suffixes, where
+// k, m and g are powers of 1000 and kb, mb and gb are powers of 1024.
+func parseMemory(value string) (int64, error) {
+	value = strings.ToLower(value)
+	factor := int64(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			factor = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("argument must be a memory value")
+	}
+	return n * factor, nil
+}
+
+func yesNo(enabled bool) string {
+	if enabled {
+		return "yes"
+	}
+	return "no"
+}
+
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("argument must be 'yes' or 'no'")
+	}
+}
+
+func (d *Dispatcher) config(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "GET":
+		if len(cmd.Args) != 2 {
+			return wrongArgs(cmd)
+		}
+		return d.configGet(ctx, strings.ToLower(cmd.Args[1]))
+	case "SET":
+		if len(cmd.Args) != 3 {
+			return wrongArgs(cmd)
+		}
+		return d.configSet(ctx, strings.ToLower(cmd.Args[1]), cmd.Args[2])
+	case "RESETSTAT":
+		if len(cmd.Args) != 1 {
+			return wrongArgs(cmd)
+		}
+		d.stats.Reset()
+		return protocol.OK
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+func (d *Dispatcher) configGet(ctx context.Context, pattern string) []string {
+	names := make([]string, 0, len(d.params))
+	for name := range d.params {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	reply := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		reply = append(reply, name, d.params[name].get(ctx))
+	}
+	return reply
+}
+
+func (d *Dispatcher) configSet(ctx context.Context, name, value string) any {
+	param, exists := d.params[name]
+	if !exists {
+		return fmt.Errorf("Unknown option or number of arguments for CONFIG SET - '%s'", name)
+	}
+	if err := param.set(ctx, value); err != nil {
+		return fmt.Errorf("CONFIG SET failed (possibly related to argument '%s') - %w", name, err)
+	}
+	return protocol.OK
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := map[string]int64{
+		"0":    0,
+		"100":  100,
+		"100b": 100,
+		"1k":   1000,
+		"1kb":  1024,
+		"2MB":  2 << 20,
+		"3m":   3_000_000,
+		"1gb":  1 << 30,
+		"1g":   1_000_000_000,
+	}
+	for value, want := range tests {
+		if got, err := parseMemory(value); err != nil || got != want {
+			t.Errorf("parseMemory(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "kb", "-1", "1tb", "1.5m"} {
+		if _, err := parseMemory(value); err == nil {
+			t.Errorf("parseMemory(%q) accepted an invalid value", value)
+		}
+	}
+}
+
+func TestConfigSetMaxMemory(t *testing.T) {
+	d, store := newTestDispatcher(t, nil, DispatcherOption{})
+	for i := 0; i < 50; i++ {
+		dispatch(t, d, "SET", "k"+strconv.Itoa(i), "value")
+	}
+	if result := dispatch(t, d, "CONFIG", "SET", "maxmemory", "1kb"); result != protocol.OK {
+		t.Fatalf("CONFIG SET maxmemory = %v", result)
+	}
+	if result := dispatch(t, d, "CONFIG", "GET", "maxmemory"); !reflect.DeepEqual(result, []string{"maxmemory", "1024"}) {
+		t.Errorf("CONFIG GET maxmemory = %#v", result)
+	}
+	if err, _ := dispatch(t, d, "SET", "new", "v").(error); !errors.Is(err, entity.ErrOOM) {
+		t.Errorf("SET after lowering maxmemory below usage = %v, want ErrOOM", err)
+	}
+	if size := store.Size(context.Background()); size != 50 {
+		t.Errorf("Size = %d, want noeviction to keep all 50 keys", size)
+	}
+	if _, failed := dispatch(t, d, "CONFIG", "SET", "maxmemory-policy", "allkeys-lru").(error); !failed {
+		t.Error("CONFIG SET maxmemory-policy allkeys-lru should fail")
+	}
+	dispatch(t, d, "CONFIG", "SET", "maxmemory", "0")
+	if result := dispatch(t, d, "SET", "new", "v"); result != protocol.OK {
+		t.Errorf("SET with maxmemory 0 = %v, want OK", result)
+	}
+}
@@ -0,0 +1,801 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+	"github.com/alexsandroveiga/redis-like-golang/internal/version"
+)
+
+type Handler func(ctx context.Context, cmd *protocol.Command) any
+
+type Interceptor func(next Handler) Handler
+
+type DispatcherOption struct {
+	EnableDebugCommand   bool
+	SlowlogLogSlowerThan int64
+	SlowlogMaxLen        int
+	Reload               func(ctx context.Context) error
+	Save                 func(ctx context.Context) error
+	CommandTimeout       time.Duration
+	DefaultTTLSeconds    int64
+}
+
+type Dispatcher struct {
+	store        repository.KeyValueRepository
+	persistence  repository.PersistenceRepository
+	pubsub       repository.PubSubRepository
+	handlers     map[command.Type]Handler
+	interceptors []Interceptor
+	chain        Handler
+	slowlog      *SlowLog
+	stats        *CommandStats
+	enableDebug  bool
+	reload       func(ctx context.Context) error
+	lastSave     atomic.Int64
+	save         func(ctx context.Context) error
+	timeout      time.Duration
+	defaultTTL   int64
+	background   backgroundQueue
+	params       map[string]configParam
+	lazyFlush    atomic.Bool
+	// keyspace is held shared by every command and by background saves, and
+	// exclusively by DEBUG RELOAD, which replaces the whole keyspace.
+	keyspace sync.RWMutex
+}
+
+func NewDispatcher(store repository.KeyValueRepository, persistence repository.PersistenceRepository, pubsub repository.PubSubRepository, opt DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		persistence: persistence,
+		pubsub:      pubsub,
+		slowlog:     NewSlowLog(opt.SlowlogLogSlowerThan, opt.SlowlogMaxLen),
+		stats:       NewCommandStats(),
+		enableDebug: opt.EnableDebugCommand,
+		reload:      opt.Reload,
+		save:        opt.Save,
+		timeout:     opt.CommandTimeout,
+		defaultTTL:  opt.DefaultTTLSeconds,
+	}
+	d.handlers = map[command.Type]Handler{
+		command.SET:      d.set,
+		command.GET:      d.get,
+		command.DEL:      d.del,
+		command.EXPIRE:   d.expire,
+		command.TTL:      d.ttl,
+		command.PEXPIRE:  d.pexpire,
+		command.PTTL:     d.pttl,
+		command.PERSIST:  d.persist,
+		command.QUIT:     d.quit,
+		command.KEYS:     d.keys,
+		command.SCAN:     d.scan,
+		command.EXISTS:   d.exists,
+		command.PING:     d.ping,
+		command.INFO:     d.info,
+		command.COMMAND:  d.command,
+		command.SLOWLOG:  d.slowlogCommand,
+		command.DEBUG:    d.debug,
+		command.OBJECT:   d.object,
+		command.TIME:     d.timeCommand,
+		command.LASTSAVE: d.lastsave,
+		command.CLUSTER:  d.cluster,
+		command.PFADD:    d.pfadd,
+		command.PFCOUNT:  d.pfcount,
+		command.PFMERGE:  d.pfmerge,
+		command.CAS:      d.cas,
+		command.PUBLISH:  d.publish,
+		command.FLUSHALL: d.flush,
+		command.FLUSHDB:  d.flush,
+		command.MEMORY:   d.memory,
+		command.CONFIG:   d.config,
+		command.WAITAOF:  d.waitaof,
+		command.BITPOS:   d.bitpos,
+		command.SETEX:    d.setex,
+		command.PSETEX:   d.setex,
+		command.LOLWUT:   d.lolwut,
+		command.BGSAVE:   d.bgsave,
+		command.FAILOVER: d.failover,
+		command.LATENCY:  d.latency,
+	}
+	d.lastSave.Store(time.Now().Unix())
+	d.params = d.configParams()
+	d.chain = d.execute
+	d.Use(d.slowlog.Interceptor(), d.stats.Interceptor())
+	return d
+}
+
+func (d *Dispatcher) Use(interceptors ...Interceptor) {
+	d.interceptors = append(d.interceptors, interceptors...)
+	chain := Handler(d.execute)
+	for i := len(d.interceptors) - 1; i >= 0; i-- {
+		chain = d.interceptors[i](chain)
+	}
+	d.chain = chain
+}
+
+func (d *Dispatcher) Dispatch(ctx context.Context, cmd *protocol.Command) (result any) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic while executing %s: %v\n%s", cmd.Type, r, debug.Stack())
+			result = fmt.Errorf("internal error")
+		}
+	}()
+	if isReload(cmd) {
+		// A reload holds the keyspace exclusively and must not be cut short
+		// by the command timeout or the client hanging up.
+		return d.chain(context.WithoutCancel(ctx), cmd)
+	}
+	if d.timeout <= 0 {
+		return d.chain(ctx, cmd)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	result = d.chain(ctx, cmd)
+	if err, failed := result.(error); failed && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("command timed out after %s", d.timeout)
+	}
+	return result
+}
+
+// RecordError counts an error replied outside Dispatch, such as an unknown
+// command rejected by the parser.
+func (d *Dispatcher) RecordError(err error) {
+	d.stats.RecordError(err)
+}
+
+func (d *Dispatcher) execute(ctx context.Context, cmd *protocol.Command) any {
+	h, exists := d.handlers[cmd.Type]
+	if !exists {
+		return fmt.Errorf("%w: %s", entity.ErrUnknownCommand, cmd.Type)
+	}
+	if isReload(cmd) {
+		d.keyspace.Lock()
+		defer d.keyspace.Unlock()
+	} else {
+		d.keyspace.RLock()
+		defer d.keyspace.RUnlock()
+	}
+	logged := cmd.Type.IsWriteCommand() && d.persistence != nil
+	if logged {
+		// Like Redis, refuse writes once the AOF has failed: the store would
+		// otherwise keep diverging from what a restart can replay.
+		if err := d.persistence.LastWriteError(); err != nil {
+			return fmt.Errorf("%w: %v", entity.ErrMisconf, err)
+		}
+	}
+	result := h(ctx, cmd)
+	if _, failed := result.(error); failed {
+		return result
+	}
+	if logged {
+		// The write is already in memory, so it must be logged even if the
+		// command deadline passed meanwhile; a failure switches the server
+		// into the read-only mode above until the AOF's retry succeeds.
+		if err := d.persistence.Append(context.WithoutCancel(ctx), cmd.Type.String(), cmd.Args); err != nil {
+			return fmt.Errorf("%w: %v", entity.ErrMisconf, err)
+		}
+	}
+	return result
+}
+
+func (d *Dispatcher) set(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) < 2 {
+		return wrongArgs(cmd)
+	}
+	opt, err := command.ParseSetOptions(cmd.Args[2:])
+	if err != nil {
+		return err
+	}
+	if d.defaultTTL > 0 && opt.TTLMillis == 0 && !opt.KeepTTL {
+		opt.TTLMillis = d.defaultTTL * 1000
+		// Spell the expiry out so the AOF replays it whatever the flag is
+		// at restart.
+		cmd.Args = append(slices.Clip(cmd.Args), "EX", strconv.FormatInt(d.defaultTTL, 10))
+	}
+	written, err := d.store.SetWithOptions(ctx, cmd.Args[0], cmd.Args[1], opt)
+	if err != nil {
+		return err
+	}
+	if !written {
+		return nil
+	}
+	return protocol.OK
+}
+
+// setex serves SETEX and PSETEX.
+func (d *Dispatcher) setex(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 3 {
+		return wrongArgs(cmd)
+	}
+	opt, err := command.SetExOptions(cmd.Type, cmd.Args[1])
+	if err != nil {
+		return err
+	}
+	if _, err := d.store.SetWithOptions(ctx, cmd.Args[0], cmd.Args[2], opt); err != nil {
+		return err
+	}
+	return protocol.OK
+}
+
+func (d *Dispatcher) get(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	value, exists := d.store.Get(ctx, cmd.Args[0])
+	if !exists {
+		return nil
+	}
+	return protocol.BulkString(value)
+}
+
+func (d *Dispatcher) del(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	return d.store.Del(ctx, cmd.Args[0])
+}
+
+func (d *Dispatcher) expire(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 2 {
+		return wrongArgs(cmd)
+	}
+	seconds, err := strconv.Atoi(cmd.Args[1])
+	if err != nil {
+		return entity.ErrNotInteger
+	}
+	return boolToInt(d.store.Expire(ctx, cmd.Args[0], seconds))
+}
+
+func (d *Dispatcher) pexpire(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 2 {
+		return wrongArgs(cmd)
+	}
+	ms, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+	if err != nil {
+		return entity.ErrNotInteger
+	}
+	return boolToInt(d.store.PExpire(ctx, cmd.Args[0], ms))
+}
+
+func (d *Dispatcher) ttl(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	return d.store.TTL(ctx, cmd.Args[0])
+}
+
+func (d *Dispatcher) pttl(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	return d.store.PTTL(ctx, cmd.Args[0])
+}
+
+func (d *Dispatcher) persist(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	return boolToInt(d.store.Persist(ctx, cmd.Args[0]))
+}
+
+func (d *Dispatcher) quit(ctx context.Context, cmd *protocol.Command) any {
+	return protocol.OK
+}
+
+func (d *Dispatcher) keys(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	keys, err := d.store.Keys(ctx, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	return keys
+}
+
+func (d *Dispatcher) scan(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) < 1 {
+		return wrongArgs(cmd)
+	}
+	cursor, err := strconv.ParseUint(cmd.Args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	pattern := "*"
+	count := 0
+	for i := 1; i < len(cmd.Args); i += 2 {
+		if i+1 >= len(cmd.Args) {
+			return entity.ErrSyntax
+		}
+		switch strings.ToUpper(cmd.Args[i]) {
+		case "MATCH":
+			pattern = cmd.Args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(cmd.Args[i+1])
+			if err != nil {
+				return entity.ErrNotInteger
+			}
+			if count < 1 {
+				return entity.ErrSyntax
+			}
+		default:
+			return entity.ErrSyntax
+		}
+	}
+	next, keys := d.store.Scan(ctx, cursor, pattern, count)
+	return protocol.Array{protocol.BulkString(strconv.FormatUint(next, 10)), keys}
+}
+
+func (d *Dispatcher) exists(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 1 {
+		return wrongArgs(cmd)
+	}
+	return boolToInt(d.store.Exists(ctx, cmd.Args[0]))
+}
+
+func (d *Dispatcher) ping(ctx context.Context, cmd *protocol.Command) any {
+	switch len(cmd.Args) {
+	case 0:
+		return protocol.SimpleString("PONG")
+	case 1:
+		return protocol.BulkString(cmd.Args[0])
+	default:
+		return wrongArgs(cmd)
+	}
+}
+
+func (d *Dispatcher) info(ctx context.Context, cmd *protocol.Command) any {
+	sections := []string{
+		fmt.Sprintf("# Memory\r\nused_memory:%d\r\nmaxmemory_policy:noeviction", d.store.UsedMemory(ctx)),
+		d.persistenceInfo(),
+		d.stats.Info(),
+		d.stats.ErrorInfo(),
+		"# Cluster\r\ncluster_enabled:0",
+		d.keyspaceInfo(ctx),
+	}
+	return protocol.BulkString(strings.Join(sections, "\r\n\r\n"))
+}
+
+func (d *Dispatcher) keyspaceInfo(ctx context.Context) string {
+	stats := d.store.KeyspaceStats(ctx)
+	if stats.Keys == 0 {
+		return "# Keyspace"
+	}
+	return fmt.Sprintf("# Keyspace\r\ndb0:keys=%d,expires=%d,avg_ttl=%d", stats.Keys, stats.Expires, stats.AvgTTL)
+}
+
+func (d *Dispatcher) persistenceInfo() string {
+	aofEnabled := 0
+	aofStatus := "ok"
+	if d.persistence != nil {
+		aofEnabled = 1
+		if d.persistence.LastWriteError() != nil {
+			aofStatus = "err"
+		}
+	}
+	info := fmt.Sprintf("# Persistence\r\naof_enabled:%d\r\nrdb_bgsave_in_progress:%d\r\nrdb_last_save_time:%d\r\naof_last_write_status:%s",
+		aofEnabled, boolToInt(d.background.current() == "save"), d.lastSave.Load(), aofStatus)
+	if d.persistence == nil {
+		return info
+	}
+	stats := d.persistence.Stats()
+	return info + fmt.Sprintf("\r\naof_rewrite_in_progress:%d\r\naof_last_rewrite_time_sec:%d\r\naof_current_size:%d\r\naof_buffer_length:%d",
+		boolToInt(stats.RewriteInProgress), stats.LastRewriteSeconds, stats.CurrentSize, stats.BufferLength)
+}
+
+func (d *Dispatcher) lastsave(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 0 {
+		return wrongArgs(cmd)
+	}
+	return d.lastSave.Load()
+}
+
+func (d *Dispatcher) bgsave(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) > 1 || (len(cmd.Args) == 1 && !strings.EqualFold(cmd.Args[0], "SCHEDULE")) {
+		return entity.ErrSyntax
+	}
+	if d.save == nil {
+		return fmt.Errorf("BGSAVE is not supported by this server")
+	}
+	err := d.background.submit("save", func() {
+		d.keyspace.RLock()
+		defer d.keyspace.RUnlock()
+		if err := d.save(context.Background()); err != nil {
+			log.Printf("background save failed: %v", err)
+			return
+		}
+		d.lastSave.Store(time.Now().Unix())
+	})
+	if errors.Is(err, errBackgroundBusy) {
+		return fmt.Errorf("Background save already in progress")
+	}
+	return protocol.SimpleString("Background saving started")
+}
+
+func isReload(cmd *protocol.Command) bool {
+	return cmd.Type == command.DEBUG && len(cmd.Args) > 0 && strings.EqualFold(cmd.Args[0], "RELOAD")
+}
+
+// Wait blocks until the running background job, if any, has finished.
+func (d *Dispatcher) Wait() {
+	d.background.wait()
+}
+
+func (d *Dispatcher) command(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "GETKEYS":
+		keys, err := command.GetKeys(cmd.Args[1:])
+		if err != nil {
+			return err
+		}
+		return keys
+	case "INFO":
+		reply := make(protocol.Array, 0, len(cmd.Args)-1)
+		for _, name := range cmd.Args[1:] {
+			cmdType := command.Type(strings.ToUpper(name))
+			if !cmdType.IsValid() {
+				reply = append(reply, nil)
+				continue
+			}
+			reply = append(reply, commandInfo(cmdType.Info()))
+		}
+		return reply
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+func commandInfo(info command.Info) protocol.Array {
+	flags := make(protocol.Array, len(info.Flags))
+	for i, flag := range info.Flags {
+		flags[i] = protocol.SimpleString(flag)
+	}
+	return protocol.Array{
+		protocol.BulkString(strings.ToLower(info.Name)),
+		info.Arity,
+		flags,
+		info.FirstKey,
+		info.LastKey,
+		info.Step,
+		protocol.Array{},
+		protocol.Array{},
+		protocol.Array{},
+		protocol.Array{},
+	}
+}
+
+func (d *Dispatcher) slowlogCommand(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "GET":
+		count := 10
+		if len(cmd.Args) > 1 {
+			n, err := strconv.Atoi(cmd.Args[1])
+			if err != nil {
+				return entity.ErrNotInteger
+			}
+			count = n
+		}
+		entries := d.slowlog.Get(count)
+		reply := make(protocol.Array, len(entries))
+		for i, entry := range entries {
+			reply[i] = protocol.Array{entry.ID, entry.Timestamp, entry.Duration.Microseconds(), entry.Args}
+		}
+		return reply
+	case "LEN":
+		return d.slowlog.Len()
+	case "RESET":
+		d.slowlog.Reset()
+		return protocol.OK
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+func (d *Dispatcher) debug(ctx context.Context, cmd *protocol.Command) any {
+	if !d.enableDebug {
+		return fmt.Errorf("DEBUG command not allowed")
+	}
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "SLEEP":
+		if len(cmd.Args) != 2 {
+			return wrongArgs(cmd)
+		}
+		seconds, err := strconv.ParseFloat(cmd.Args[1], 64)
+		if err != nil {
+			return fmt.Errorf("value is not a valid float")
+		}
+		select {
+		case <-time.After(time.Duration(seconds * float64(time.Second))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return protocol.OK
+	case "OBJECT":
+		if len(cmd.Args) != 2 {
+			return wrongArgs(cmd)
+		}
+		info, exists := d.store.Object(ctx, cmd.Args[1])
+		if !exists {
+			return entity.ErrNoSuchKey
+		}
+		return protocol.SimpleString(fmt.Sprintf("Value at:0x0 refcount:1 encoding:%s serializedlength:%d lru:0 lru_seconds_idle:%d", info.Encoding, info.StoredLength, info.IdleTime))
+	case "RELOAD":
+		if d.reload == nil {
+			return fmt.Errorf("DEBUG RELOAD is not supported by this server")
+		}
+		if err := d.reload(ctx); err != nil {
+			return fmt.Errorf("Error trying to load the dump: %w", err)
+		}
+		return protocol.OK
+	case "SET-ACTIVE-EXPIRE":
+		if len(cmd.Args) != 2 {
+			return wrongArgs(cmd)
+		}
+		switch cmd.Args[1] {
+		case "0":
+			d.store.SetActiveExpire(false)
+		case "1":
+			d.store.SetActiveExpire(true)
+		default:
+			return entity.ErrSyntax
+		}
+		return protocol.OK
+	// Accepted for test harness compatibility; there is no replication ID,
+	// JVM-style heap map or quicklist to act on.
+	case "CHANGE-REPL-ID", "JMAP", "QUICKLIST-PACKED-THRESHOLD":
+		return protocol.OK
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+func (d *Dispatcher) object(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	subcommand := strings.ToUpper(cmd.Args[0])
+	switch subcommand {
+	case "HELP":
+		return help(cmd.Type)
+	case "ENCODING", "IDLETIME", "REFCOUNT", "FREQ":
+	default:
+		return unknownSubcommand(cmd)
+	}
+	if len(cmd.Args) != 2 {
+		return wrongArgs(cmd)
+	}
+	info, exists := d.store.Object(ctx, cmd.Args[1])
+	if !exists {
+		return entity.ErrNoSuchKey
+	}
+	switch subcommand {
+	case "IDLETIME":
+		return info.IdleTime
+	case "ENCODING":
+		return protocol.BulkString(info.Encoding)
+	case "REFCOUNT":
+		return 1
+	default:
+		return fmt.Errorf("An LFU maxmemory policy is not selected, access frequency not tracked")
+	}
+}
+
+func (d *Dispatcher) timeCommand(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 0 {
+		return wrongArgs(cmd)
+	}
+	now := time.Now()
+	return []string{
+		strconv.FormatInt(now.Unix(), 10),
+		strconv.FormatInt(int64(now.Nanosecond()/1000), 10),
+	}
+}
+
+func (d *Dispatcher) cluster(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "INFO":
+		return protocol.BulkString("cluster_enabled:0\r\n")
+	case "SLOTS":
+		return protocol.Array{}
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+func (d *Dispatcher) pfadd(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) < 1 {
+		return wrongArgs(cmd)
+	}
+	changed, err := d.store.PFAdd(ctx, cmd.Args[0], cmd.Args[1:]...)
+	if err != nil {
+		return err
+	}
+	return changed
+}
+
+func (d *Dispatcher) pfcount(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) < 1 {
+		return wrongArgs(cmd)
+	}
+	count, err := d.store.PFCount(ctx, cmd.Args...)
+	if err != nil {
+		return err
+	}
+	return count
+}
+
+func (d *Dispatcher) pfmerge(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) < 1 {
+		return wrongArgs(cmd)
+	}
+	if err := d.store.PFMerge(ctx, cmd.Args[0], cmd.Args[1:]...); err != nil {
+		return err
+	}
+	return protocol.OK
+}
+
+func (d *Dispatcher) cas(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 3 {
+		return wrongArgs(cmd)
+	}
+	swapped, err := d.store.CompareAndSet(ctx, cmd.Args[0], cmd.Args[1], cmd.Args[2])
+	if err != nil {
+		return err
+	}
+	return boolToInt(swapped)
+}
+
+func (d *Dispatcher) bitpos(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) < 2 || len(cmd.Args) > 4 {
+		return wrongArgs(cmd)
+	}
+	bit, err := strconv.Atoi(cmd.Args[1])
+	if err != nil || (bit != 0 && bit != 1) {
+		return fmt.Errorf("The bit argument must be 1 or 0.")
+	}
+	var start, end int
+	if len(cmd.Args) > 2 {
+		if start, err = strconv.Atoi(cmd.Args[2]); err != nil {
+			return entity.ErrNotInteger
+		}
+	}
+	if len(cmd.Args) > 3 {
+		if end, err = strconv.Atoi(cmd.Args[3]); err != nil {
+			return entity.ErrNotInteger
+		}
+	}
+	return d.store.BitPos(ctx, cmd.Args[0], bit, start, end, len(cmd.Args) > 3)
+}
+
+func (d *Dispatcher) lolwut(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 0 && (len(cmd.Args) != 2 || !strings.EqualFold(cmd.Args[0], "VERSION")) {
+		return entity.ErrSyntax
+	}
+	return protocol.BulkString(fmt.Sprintf("redis-like-golang ver. %s\n", version.Version))
+}
+
+func (d *Dispatcher) publish(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 2 {
+		return wrongArgs(cmd)
+	}
+	return d.pubsub.Publish(ctx, cmd.Args[0], cmd.Args[1])
+}
+
+// flush serves both FLUSHALL and FLUSHDB, since there is a single database.
+// ASYNC and SYNC are accepted but behave alike: the store never frees the old
+// keyspace on the request path.
+func (d *Dispatcher) flush(ctx context.Context, cmd *protocol.Command) any {
+	switch len(cmd.Args) {
+	case 0:
+	case 1:
+		if !strings.EqualFold(cmd.Args[0], "ASYNC") && !strings.EqualFold(cmd.Args[0], "SYNC") {
+			return entity.ErrSyntax
+		}
+	default:
+		return entity.ErrSyntax
+	}
+	d.store.FlushAll(ctx)
+	return protocol.OK
+}
+
+func (d *Dispatcher) memory(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) == 0 {
+		return wrongArgs(cmd)
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HELP":
+		return help(cmd.Type)
+	case "PURGE":
+		if len(cmd.Args) != 1 {
+			return wrongArgs(cmd)
+		}
+		d.store.Shrink(ctx)
+		return protocol.OK
+	case "USAGE":
+		if len(cmd.Args) != 2 && (len(cmd.Args) != 4 || !strings.EqualFold(cmd.Args[2], "SAMPLES")) {
+			return entity.ErrSyntax
+		}
+		info, exists := d.store.Object(ctx, cmd.Args[1])
+		if !exists {
+			return nil
+		}
+		return info.MemoryUsage
+	default:
+		return unknownSubcommand(cmd)
+	}
+}
+
+// waitaof issues an fsync barrier: once SyncAOF returns, every write this
+// client has seen is durable locally. There are no replicas, so the second
+// count is always 0 and the timeout never comes into play.
+func (d *Dispatcher) waitaof(ctx context.Context, cmd *protocol.Command) any {
+	if len(cmd.Args) != 3 {
+		return wrongArgs(cmd)
+	}
+	numLocal, err := strconv.Atoi(cmd.Args[0])
+	if err != nil || numLocal < 0 {
+		return entity.ErrNotInteger
+	}
+	if _, err := strconv.Atoi(cmd.Args[1]); err != nil {
+		return entity.ErrNotInteger
+	}
+	if timeout, err := strconv.Atoi(cmd.Args[2]); err != nil || timeout < 0 {
+		return fmt.Errorf("timeout is not an integer or out of range")
+	}
+	if numLocal > 0 && d.persistence == nil {
+		return fmt.Errorf("WAITAOF cannot be used when numlocal is set but appendonly is disabled.")
+	}
+	synced := 0
+	if d.persistence != nil && d.persistence.LastWriteError() == nil && d.persistence.SyncAOF() == nil {
+		synced = 1
+	}
+	return protocol.Array{synced, 0}
+}
+
+func wrongArgs(cmd *protocol.Command) error {
+	return fmt.Errorf("%w for '%s' command", entity.ErrWrongArgs, strings.ToLower(cmd.Type.String()))
+}
+
+func unknownSubcommand(cmd *protocol.Command) error {
+	return fmt.Errorf("unknown subcommand '%s' for '%s'", cmd.Args[0], strings.ToLower(cmd.Type.String()))
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
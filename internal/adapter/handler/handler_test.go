@@ -0,0 +1,764 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/clock"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/persistence"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/storage"
+	"github.com/alexsandroveiga/redis-like-golang/internal/version"
+)
+
+func newTestDispatcher(t *testing.T, persistence repository.PersistenceRepository, opt DispatcherOption) (*Dispatcher, repository.KeyValueRepository) {
+	t.Helper()
+	store := storage.NewStore(storage.StoreOption{})
+	d := NewDispatcher(store, persistence, nil, opt)
+	t.Cleanup(d.Wait)
+	return d, store
+}
+
+func dispatch(t *testing.T, d *Dispatcher, args ...string) any {
+	t.Helper()
+	cmd, err := protocol.NewParser().ParseArgs(args)
+	if err != nil {
+		t.Fatalf("parse %v: %v", args, err)
+	}
+	return d.Dispatch(context.Background(), cmd)
+}
+
+func TestReloadExcludesOtherCommands(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	d, store := newTestDispatcher(t, nil, DispatcherOption{
+		EnableDebugCommand: true,
+		Reload: func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		},
+	})
+	reloaded := make(chan any)
+	go func() { reloaded <- dispatch(t, d, "DEBUG", "RELOAD") }()
+	<-started
+
+	written := make(chan any)
+	go func() { written <- dispatch(t, d, "SET", "k", "v") }()
+	select {
+	case <-written:
+		t.Fatal("SET ran while DEBUG RELOAD held the keyspace")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if result := <-reloaded; result != protocol.OK {
+		t.Fatalf("DEBUG RELOAD = %v", result)
+	}
+	if result := <-written; result != protocol.OK {
+		t.Fatalf("SET = %v", result)
+	}
+	if value, _ := store.Get(context.Background(), "k"); value != "v" {
+		t.Errorf("k = %q after reload, want v", value)
+	}
+}
+
+func TestWaitAOF(t *testing.T) {
+	aof, err := persistence.NewAOF(filepath.Join(t.TempDir(), "appendonly.aof"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	d, _ := newTestDispatcher(t, aof, DispatcherOption{})
+	if result := dispatch(t, d, "SET", "k", "v"); result != protocol.OK {
+		t.Fatalf("SET = %v", result)
+	}
+	result := dispatch(t, d, "WAITAOF", "1", "0", "0")
+	if !reflect.DeepEqual(result, protocol.Array{1, 0}) {
+		t.Errorf("WAITAOF with AOF = %v, want [1 0]", result)
+	}
+
+	d, _ = newTestDispatcher(t, nil, DispatcherOption{})
+	if _, failed := dispatch(t, d, "WAITAOF", "1", "0", "0").(error); !failed {
+		t.Error("WAITAOF 1 without AOF should fail")
+	}
+	result = dispatch(t, d, "WAITAOF", "0", "0", "0")
+	if !reflect.DeepEqual(result, protocol.Array{0, 0}) {
+		t.Errorf("WAITAOF 0 without AOF = %v, want [0 0]", result)
+	}
+}
+
+type fakeAOF struct {
+	err     error
+	appends int
+}
+
+func (f *fakeAOF) Append(ctx context.Context, command string, args []string) error {
+	f.appends++
+	return f.err
+}
+
+func (f *fakeAOF) Replay(ctx context.Context, store repository.KeyValueRepository) error {
+	return nil
+}
+
+func (f *fakeAOF) SyncAOF() error { return f.err }
+
+func (f *fakeAOF) LastWriteError() error {
+	if f.appends == 0 {
+		return nil
+	}
+	return f.err
+}
+
+func (f *fakeAOF) Stats() repository.PersistenceStats { return repository.PersistenceStats{} }
+
+func (f *fakeAOF) Close() error { return nil }
+
+func TestWritesRefusedAfterAOFFailure(t *testing.T) {
+	aof := &fakeAOF{err: errors.New("no space left on device")}
+	d, store := newTestDispatcher(t, aof, DispatcherOption{})
+	if err, _ := dispatch(t, d, "SET", "a", "1").(error); !errors.Is(err, entity.ErrMisconf) {
+		t.Fatalf("SET with failing append = %v, want MISCONF", err)
+	}
+	if err, _ := dispatch(t, d, "SET", "b", "2").(error); !errors.Is(err, entity.ErrMisconf) {
+		t.Fatalf("SET after failure = %v, want MISCONF", err)
+	}
+	if store.Exists(context.Background(), "b") {
+		t.Error("write refused with MISCONF still reached the store")
+	}
+	if aof.appends != 1 {
+		t.Errorf("appends = %d, want 1", aof.appends)
+	}
+	if result := dispatch(t, d, "GET", "a"); result != protocol.BulkString("1") {
+		t.Errorf("GET after failure = %v, want 1", result)
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	var calls []string
+	trace := func(name string) Interceptor {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, cmd *protocol.Command) any {
+				calls = append(calls, name+" before")
+				result := next(ctx, cmd)
+				calls = append(calls, name+" after")
+				return result
+			}
+		}
+	}
+	d.Use(trace("outer"), trace("inner"))
+	if result := dispatch(t, d, "PING"); result != protocol.SimpleString("PONG") {
+		t.Fatalf("PING = %v", result)
+	}
+	want := []string{"outer before", "inner before", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestWriteNearDeadlineIsLogged(t *testing.T) {
+	aof := &fakeAOF{}
+	d, _ := newTestDispatcher(t, aof, DispatcherOption{CommandTimeout: time.Millisecond})
+	d.handlers[command.SET] = func(ctx context.Context, cmd *protocol.Command) any {
+		<-ctx.Done()
+		return protocol.OK
+	}
+	if result := dispatch(t, d, "SET", "k", "v"); result != protocol.OK {
+		t.Fatalf("SET finishing after the deadline = %v, want OK", result)
+	}
+	if aof.appends != 1 {
+		t.Errorf("appends = %d, want the applied write to be logged", aof.appends)
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	ctx := context.Background()
+	d, store := newTestDispatcher(t, nil, DispatcherOption{DefaultTTLSeconds: 100})
+	dispatch(t, d, "SET", "default", "v")
+	if ttl := store.TTL(ctx, "default"); ttl != 100 {
+		t.Errorf("TTL after plain SET = %d, want 100", ttl)
+	}
+	dispatch(t, d, "SET", "explicit", "v", "EX", "10")
+	if ttl := store.TTL(ctx, "explicit"); ttl != 10 {
+		t.Errorf("TTL after SET EX 10 = %d, want 10", ttl)
+	}
+	dispatch(t, d, "PERSIST", "default")
+	if ttl := store.TTL(ctx, "default"); ttl != -1 {
+		t.Errorf("TTL after PERSIST = %d, want -1", ttl)
+	}
+	dispatch(t, d, "SET", "default", "w", "KEEPTTL")
+	if ttl := store.TTL(ctx, "default"); ttl != -1 {
+		t.Errorf("TTL after SET KEEPTTL = %d, want -1", ttl)
+	}
+	dispatch(t, d, "CAS", "cas", "", "v")
+	if ttl := store.TTL(ctx, "cas"); ttl != -1 {
+		t.Errorf("TTL after CAS = %d, want -1", ttl)
+	}
+	if err := store.SetMany(ctx, map[string]string{"many": "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := store.TTL(ctx, "many"); ttl != -1 {
+		t.Errorf("TTL after SetMany = %d, want -1", ttl)
+	}
+}
+
+func TestDefaultTTLIsLogged(t *testing.T) {
+	aof, err := persistence.NewAOF(filepath.Join(t.TempDir(), "appendonly.aof"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	d, _ := newTestDispatcher(t, aof, DispatcherOption{DefaultTTLSeconds: 100})
+	dispatch(t, d, "SET", "k", "v")
+
+	ctx := context.Background()
+	store := storage.NewStore(storage.StoreOption{})
+	if err := aof.Replay(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := store.TTL(ctx, "k"); ttl != 100 {
+		t.Errorf("replayed TTL = %d, want 100", ttl)
+	}
+}
+
+func TestConfigKeysLimit(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	dispatch(t, d, "SET", "a", "1")
+	dispatch(t, d, "SET", "b", "2")
+	if result := dispatch(t, d, "CONFIG", "SET", "keys-limit", "1"); result != protocol.OK {
+		t.Fatalf("CONFIG SET keys-limit = %v", result)
+	}
+	if _, failed := dispatch(t, d, "KEYS", "*").(error); !failed {
+		t.Error("KEYS over keys-limit should fail")
+	}
+	result := dispatch(t, d, "CONFIG", "GET", "keys-limit")
+	if !reflect.DeepEqual(result, []string{"keys-limit", "1"}) {
+		t.Errorf("CONFIG GET keys-limit = %#v", result)
+	}
+}
+
+func TestDispatchRecoversFromPanic(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	d.handlers[command.GET] = func(ctx context.Context, cmd *protocol.Command) any {
+		panic("boom")
+	}
+	if _, failed := dispatch(t, d, "GET", "k").(error); !failed {
+		t.Fatal("panicking handler should reply with an error")
+	}
+	if result := dispatch(t, d, "SET", "k", "v"); result != protocol.OK {
+		t.Errorf("SET after a panic = %v, want OK", result)
+	}
+}
+
+func TestConcurrentBGSaveRejected(t *testing.T) {
+	release := make(chan struct{})
+	saves := 0
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{
+		Save: func(ctx context.Context) error {
+			saves++
+			<-release
+			return nil
+		},
+	})
+	if result := dispatch(t, d, "BGSAVE"); result != protocol.SimpleString("Background saving started") {
+		t.Fatalf("first BGSAVE = %v", result)
+	}
+	err, _ := dispatch(t, d, "BGSAVE").(error)
+	if err == nil || err.Error() != "Background save already in progress" {
+		t.Errorf("second BGSAVE = %v, want it rejected", err)
+	}
+	close(release)
+	d.Wait()
+	if saves != 1 {
+		t.Errorf("saves = %d, want 1", saves)
+	}
+	if result := dispatch(t, d, "BGSAVE"); result != protocol.SimpleString("Background saving started") {
+		t.Errorf("BGSAVE after the first finished = %v", result)
+	}
+}
+
+func TestFlushAll(t *testing.T) {
+	d, store := newTestDispatcher(t, nil, DispatcherOption{})
+	for _, mode := range []string{"ASYNC", "SYNC"} {
+		dispatch(t, d, "SET", "k", "v")
+		if result := dispatch(t, d, "FLUSHALL", mode); result != protocol.OK {
+			t.Fatalf("FLUSHALL %s = %v", mode, result)
+		}
+		if size := store.Size(context.Background()); size != 0 {
+			t.Errorf("FLUSHALL %s left %d keys", mode, size)
+		}
+	}
+	if _, failed := dispatch(t, d, "FLUSHDB", "LATER").(error); !failed {
+		t.Error("FLUSHDB with an unknown mode should fail")
+	}
+}
+
+func TestObjectEncoding(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	dispatch(t, d, "SET", "int", "42")
+	dispatch(t, d, "SET", "numeric", "123abc")
+	dispatch(t, d, "SET", "short", "hello")
+	dispatch(t, d, "SET", "long", strings.Repeat("x", 100))
+	for key, want := range map[string]string{"int": "int", "numeric": "embstr", "short": "embstr", "long": "raw"} {
+		if result := dispatch(t, d, "OBJECT", "ENCODING", key); result != protocol.BulkString(want) {
+			t.Errorf("OBJECT ENCODING %s = %v, want %s", key, result, want)
+		}
+	}
+	if usage, _ := dispatch(t, d, "MEMORY", "USAGE", "long").(int64); usage <= 100 {
+		t.Errorf("MEMORY USAGE long = %d, want more than the value length", usage)
+	}
+	if result := dispatch(t, d, "MEMORY", "USAGE", "missing"); result != nil {
+		t.Errorf("MEMORY USAGE missing = %v, want nil", result)
+	}
+}
+
+func TestTime(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	before := time.Now()
+	reply, ok := dispatch(t, d, "TIME").([]string)
+	after := time.Now()
+	if !ok || len(reply) != 2 {
+		t.Fatalf("TIME = %v, want seconds and microseconds", reply)
+	}
+	seconds, err := strconv.ParseInt(reply[0], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	micros, err := strconv.ParseInt(reply[1], 10, 64)
+	if err != nil || micros < 0 || micros >= 1_000_000 {
+		t.Fatalf("TIME microseconds = %q", reply[1])
+	}
+	got := time.Unix(seconds, micros*1000)
+	if got.Before(before.Truncate(time.Microsecond)) || got.After(after) {
+		t.Errorf("TIME = %v, want between %v and %v", got, before, after)
+	}
+}
+
+// infoField returns the value of field in the INFO reply, or "" if absent.
+func infoField(t *testing.T, d *Dispatcher, field string) string {
+	t.Helper()
+	info, _ := dispatch(t, d, "INFO").(protocol.BulkString)
+	for _, line := range strings.Split(string(info), "\r\n") {
+		if value, found := strings.CutPrefix(line, field+":"); found {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestLastSaveAdvancesAfterBGSave(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{
+		Save: func(ctx context.Context) error { return nil },
+	})
+	d.lastSave.Store(0)
+	if result := dispatch(t, d, "LASTSAVE"); result != int64(0) {
+		t.Fatalf("LASTSAVE = %v, want 0", result)
+	}
+	before := time.Now().Unix()
+	dispatch(t, d, "BGSAVE")
+	d.Wait()
+	last, _ := dispatch(t, d, "LASTSAVE").(int64)
+	if last < before {
+		t.Errorf("LASTSAVE = %d after BGSAVE, want at least %d", last, before)
+	}
+	if field := infoField(t, d, "rdb_last_save_time"); field != strconv.FormatInt(last, 10) {
+		t.Errorf("rdb_last_save_time = %s, want %d", field, last)
+	}
+	if field := infoField(t, d, "rdb_bgsave_in_progress"); field != "0" {
+		t.Errorf("rdb_bgsave_in_progress = %s after the save, want 0", field)
+	}
+}
+
+func TestLastSaveKeptAfterFailedSave(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{
+		Save: func(ctx context.Context) error { return errors.New("disk full") },
+	})
+	d.lastSave.Store(0)
+	dispatch(t, d, "BGSAVE")
+	d.Wait()
+	if result := dispatch(t, d, "LASTSAVE"); result != int64(0) {
+		t.Errorf("LASTSAVE = %v after a failed save, want it unchanged", result)
+	}
+}
+
+func TestWritesRefusedOverMaxMemory(t *testing.T) {
+	ctx := context.Background()
+	d, store := newTestDispatcher(t, nil, DispatcherOption{})
+	store.SetMaxMemory(4096)
+	value := strings.Repeat("x", 100)
+	var err error
+	keys := 0
+	for ; keys < 1000 && err == nil; keys++ {
+		err, _ = dispatch(t, d, "SET", "k"+strconv.Itoa(keys), value).(error)
+	}
+	if !errors.Is(err, entity.ErrOOM) {
+		t.Fatalf("SET over maxmemory = %v, want ErrOOM", err)
+	}
+	want := "-OOM command not allowed when used memory > 'maxmemory'.\r\n"
+	if reply := protocol.NewParser().FormatError(err); reply != want {
+		t.Errorf("reply = %q, want %q", reply, want)
+	}
+	if result := dispatch(t, d, "GET", "k0"); result != protocol.BulkString(value) {
+		t.Errorf("GET over maxmemory = %v, want the value", result)
+	}
+	for i := 0; i < keys; i++ {
+		dispatch(t, d, "DEL", "k"+strconv.Itoa(i))
+	}
+	if size := store.Size(ctx); size != 0 {
+		t.Fatalf("DEL over maxmemory left %d keys", size)
+	}
+	if result := dispatch(t, d, "SET", "k", "v"); result != protocol.OK {
+		t.Errorf("SET after freeing memory = %v, want OK", result)
+	}
+}
+
+func TestDebugObject(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{EnableDebugCommand: true})
+	dispatch(t, d, "SET", "k", "hello")
+	reply, _ := dispatch(t, d, "DEBUG", "OBJECT", "k").(protocol.SimpleString)
+	for _, field := range []string{"refcount:1", "encoding:embstr", "serializedlength:5"} {
+		if !strings.Contains(string(reply), field) {
+			t.Errorf("DEBUG OBJECT = %q, want it to contain %s", reply, field)
+		}
+	}
+	if err, _ := dispatch(t, d, "DEBUG", "OBJECT", "missing").(error); !errors.Is(err, entity.ErrNoSuchKey) {
+		t.Errorf("DEBUG OBJECT missing = %v, want ErrNoSuchKey", err)
+	}
+
+	d, _ = newTestDispatcher(t, nil, DispatcherOption{})
+	if _, failed := dispatch(t, d, "DEBUG", "OBJECT", "k").(error); !failed {
+		t.Error("DEBUG OBJECT ran with DEBUG disabled")
+	}
+}
+
+// dispatchRESP returns the reply to args as it is written to the client.
+func dispatchRESP(t *testing.T, d *Dispatcher, args ...string) string {
+	t.Helper()
+	return protocol.NewParser().FormatResponse(dispatch(t, d, args...))
+}
+
+func TestClusterStub(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"CLUSTER", "INFO"}, "$19\r\ncluster_enabled:0\r\n\r\n"},
+		{[]string{"CLUSTER", "SLOTS"}, "*0\r\n"},
+		{[]string{"CLUSTER", "NODES"}, "-ERR unknown subcommand 'NODES' for 'cluster'\r\n"},
+	}
+	for _, tt := range tests {
+		if got := dispatchRESP(t, d, tt.args...); got != tt.want {
+			t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if field := infoField(t, d, "cluster_enabled"); field != "0" {
+		t.Errorf("INFO cluster_enabled = %q, want 0", field)
+	}
+}
+
+func TestReplyTypes(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"GET", "missing"}, "$-1\r\n"},
+		{[]string{"SET", "k", "1"}, "+OK\r\n"},
+		{[]string{"GET", "k"}, "$1\r\n1\r\n"},
+		{[]string{"EXISTS", "k"}, ":1\r\n"},
+		{[]string{"EXISTS", "missing"}, ":0\r\n"},
+	}
+	for _, tt := range tests {
+		if got := dispatchRESP(t, d, tt.args...); got != tt.want {
+			t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestConfigCleanupInterval(t *testing.T) {
+	d, store := newTestDispatcher(t, nil, DispatcherOption{})
+	if result := dispatch(t, d, "CONFIG", "SET", "cleanup-interval-ms", "250"); result != protocol.OK {
+		t.Fatalf("CONFIG SET cleanup-interval-ms = %v", result)
+	}
+	if interval := store.CleanupInterval(); interval != 250 {
+		t.Errorf("cleanup interval = %d, want 250", interval)
+	}
+	result := dispatch(t, d, "CONFIG", "GET", "cleanup-interval-ms")
+	if !reflect.DeepEqual(result, []string{"cleanup-interval-ms", "250"}) {
+		t.Errorf("CONFIG GET cleanup-interval-ms = %#v", result)
+	}
+	if _, failed := dispatch(t, d, "CONFIG", "SET", "cleanup-interval-ms", "-1").(error); !failed {
+		t.Error("CONFIG SET cleanup-interval-ms -1 should fail")
+	}
+}
+
+func TestAOFSizeGrowsInInfo(t *testing.T) {
+	aof, err := persistence.NewAOF(filepath.Join(t.TempDir(), "appendonly.aof"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	d, _ := newTestDispatcher(t, aof, DispatcherOption{})
+	if field := infoField(t, d, "aof_enabled"); field != "1" {
+		t.Fatalf("aof_enabled = %q, want 1", field)
+	}
+	size := func() int {
+		n, err := strconv.Atoi(infoField(t, d, "aof_current_size"))
+		if err != nil {
+			t.Fatalf("aof_current_size: %v", err)
+		}
+		return n
+	}
+	before := size()
+	dispatch(t, d, "SET", "k", "v")
+	afterSet := size()
+	if afterSet <= before {
+		t.Fatalf("aof_current_size = %d after SET, want more than %d", afterSet, before)
+	}
+	dispatch(t, d, "GET", "k")
+	if afterGet := size(); afterGet != afterSet {
+		t.Errorf("aof_current_size = %d after GET, want it unchanged at %d", afterGet, afterSet)
+	}
+	if field := infoField(t, d, "aof_rewrite_in_progress"); field != "0" {
+		t.Errorf("aof_rewrite_in_progress = %q, want 0", field)
+	}
+}
+
+func TestDebugReloadPreservesDataset(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewStore(storage.StoreOption{})
+	d := NewDispatcher(store, nil, nil, DispatcherOption{
+		EnableDebugCommand: true,
+		Reload: func(ctx context.Context) error {
+			return persistence.Reload(ctx, store)
+		},
+	})
+	t.Cleanup(d.Wait)
+	dispatch(t, d, "SET", "plain", "v")
+	dispatch(t, d, "SET", "ttl", "v", "EX", "100")
+	dispatch(t, d, "PFADD", "hll", "a", "b")
+	want := store.SnapshotKeysWithTTL(ctx)
+	hll, _ := store.Get(ctx, "hll")
+
+	if result := dispatch(t, d, "DEBUG", "RELOAD"); result != protocol.OK {
+		t.Fatalf("DEBUG RELOAD = %v", result)
+	}
+	got := store.SnapshotKeysWithTTL(ctx)
+	if len(got) != len(want) {
+		t.Fatalf("keys after reload = %v, want %v", got, want)
+	}
+	for key, ttl := range want {
+		if reloaded, exists := got[key]; !exists || reloaded < ttl-1 || reloaded > ttl {
+			t.Errorf("%s TTL = %d, %v after reload; want %d", key, reloaded, exists, ttl)
+		}
+	}
+	if value, _ := store.Get(ctx, "hll"); value != hll {
+		t.Error("HyperLogLog registers changed across DEBUG RELOAD")
+	}
+	if result := dispatch(t, d, "DEBUG", "JMAP"); result != protocol.OK {
+		t.Errorf("DEBUG JMAP = %v, want OK", result)
+	}
+	if _, failed := dispatch(t, d, "DEBUG", "NO-SUCH-THING").(error); !failed {
+		t.Error("unknown DEBUG subcommand should fail")
+	}
+}
+
+func TestLolwutReportsVersion(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	for _, args := range [][]string{{"LOLWUT"}, {"LOLWUT", "VERSION", "5"}} {
+		reply, _ := dispatch(t, d, args...).(protocol.BulkString)
+		if !strings.Contains(string(reply), version.Version) {
+			t.Errorf("%v = %q, want it to contain version %s", args, reply, version.Version)
+		}
+	}
+	if _, failed := dispatch(t, d, "LOLWUT", "BANNER").(error); !failed {
+		t.Error("LOLWUT with an unknown argument should fail")
+	}
+}
+
+func TestInfoKeyspace(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	if field := infoField(t, d, "db0"); field != "" {
+		t.Errorf("db0 = %q on an empty keyspace, want no line", field)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		dispatch(t, d, "SET", key, "v")
+	}
+	dispatch(t, d, "EXPIRE", "a", "100")
+	dispatch(t, d, "EXPIRE", "b", "200")
+	field := infoField(t, d, "db0")
+	if !strings.HasPrefix(field, "keys=3,expires=2,avg_ttl=") {
+		t.Fatalf("db0 = %q, want keys=3,expires=2", field)
+	}
+	avg, err := strconv.ParseInt(strings.TrimPrefix(field, "keys=3,expires=2,avg_ttl="), 10, 64)
+	if err != nil || avg < 149_000 || avg > 150_000 {
+		t.Errorf("avg_ttl = %d ms, want about 150000", avg)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"SET", "k", "v", "EX", "abc"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "k", "v", "PX", "1.5"}, "-ERR value is not an integer or out of range\r\n"},
+		{[]string{"SET", "k", "v", "EX", "0"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"SET", "k", "v", "EX", "-1"}, "-ERR invalid expire time in 'set' command\r\n"},
+		{[]string{"SET", "k", "v", "EX"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "k", "v", "BOGUS"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "k", "v", "NX", "XX"}, "-ERR syntax error\r\n"},
+		{[]string{"SET", "k", "v", "EX", "10", "PX", "100"}, "-ERR syntax error\r\n"},
+		{[]string{"EXPIRE", "k", "abc"}, "-ERR value is not an integer or out of range\r\n"},
+	}
+	for _, tt := range tests {
+		if got := dispatchRESP(t, d, tt.args...); got != tt.want {
+			t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPSetExExpiresWithTheClock(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock(time.Unix(1_700_000_000, 0))
+	store := storage.NewStore(storage.StoreOption{Clock: clk})
+	d := NewDispatcher(store, nil, nil, DispatcherOption{})
+	t.Cleanup(d.Wait)
+	if result := dispatch(t, d, "PSETEX", "k", "100", "v"); result != protocol.OK {
+		t.Fatalf("PSETEX = %v", result)
+	}
+	if ttl := store.PTTL(ctx, "k"); ttl != 100 {
+		t.Errorf("PTTL after PSETEX 100 = %d, want 100", ttl)
+	}
+	clk.Advance(100 * time.Millisecond)
+	if !store.Exists(ctx, "k") {
+		t.Fatal("k expired before its 100ms ran out")
+	}
+	clk.Advance(time.Millisecond)
+	if result := dispatch(t, d, "GET", "k"); result != nil {
+		t.Errorf("GET 101ms after PSETEX 100 = %v, want nil", result)
+	}
+}
+
+func TestPExpireAndPTTL(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock(time.Unix(1_700_000_000, 0))
+	store := storage.NewStore(storage.StoreOption{Clock: clk})
+	d := NewDispatcher(store, nil, nil, DispatcherOption{})
+	t.Cleanup(d.Wait)
+	dispatch(t, d, "SET", "k", "v")
+	if got := dispatch(t, d, "PEXPIRE", "k", "1500"); got != 1 {
+		t.Fatalf("PEXPIRE = %v, want 1", got)
+	}
+	clk.Advance(400 * time.Millisecond)
+	if got := dispatch(t, d, "PTTL", "k"); got != int64(1100) {
+		t.Errorf("PTTL = %v, want 1100", got)
+	}
+	if got := dispatch(t, d, "TTL", "k"); got != int64(1) {
+		t.Errorf("TTL = %v, want 1", got)
+	}
+	if got := dispatch(t, d, "PEXPIRE", "missing", "100"); got != 0 {
+		t.Errorf("PEXPIRE missing = %v, want 0", got)
+	}
+	if got := dispatch(t, d, "PEXPIRE", "k", "-1"); got != 1 || store.Exists(ctx, "k") {
+		t.Errorf("PEXPIRE -1 = %v, want the key deleted", got)
+	}
+}
+
+func TestSlowCommandCancelledAtDeadline(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{
+		EnableDebugCommand: true,
+		CommandTimeout:     20 * time.Millisecond,
+	})
+	start := time.Now()
+	err, _ := dispatch(t, d, "DEBUG", "SLEEP", "5").(error)
+	elapsed := time.Since(start)
+	if err == nil || err.Error() != "command timed out after 20ms" {
+		t.Fatalf("DEBUG SLEEP 5 = %v, want the timeout error", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("DEBUG SLEEP 5 ran for %s with a 20ms timeout", elapsed)
+	}
+	if result := dispatch(t, d, "PING"); result != protocol.SimpleString("PONG") {
+		t.Errorf("PING after a timed-out command = %v", result)
+	}
+}
+
+func TestDebugReloadIgnoresCommandTimeout(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewStore(storage.StoreOption{})
+	d := NewDispatcher(store, nil, nil, DispatcherOption{
+		EnableDebugCommand: true,
+		CommandTimeout:     time.Nanosecond,
+		Reload: func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			return persistence.Reload(ctx, store)
+		},
+	})
+	t.Cleanup(d.Wait)
+	for i := range 100 {
+		if err := store.Set(ctx, "k"+strconv.Itoa(i), "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if result := dispatch(t, d, "DEBUG", "RELOAD"); result != protocol.OK {
+		t.Fatalf("DEBUG RELOAD with a 1ns timeout = %v, want OK", result)
+	}
+	if size := store.Size(ctx); size != 100 {
+		t.Errorf("Size after DEBUG RELOAD = %d, want 100", size)
+	}
+}
+
+func TestCommandInfo(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	reply, _ := dispatch(t, d, "COMMAND", "INFO", "get", "set", "keys", "scan", "nosuchcommand").(protocol.Array)
+	if len(reply) != 5 {
+		t.Fatalf("COMMAND INFO = %v, want five entries", reply)
+	}
+	tests := []struct {
+		name    string
+		arity   int
+		flag    protocol.SimpleString
+		keySpec protocol.Array
+	}{
+		{"get", 2, "readonly", protocol.Array{1, 1, 1}},
+		{"set", -3, "write", protocol.Array{1, 1, 1}},
+		{"keys", 2, "readonly", protocol.Array{0, 0, 0}},
+		{"scan", -2, "readonly", protocol.Array{0, 0, 0}},
+	}
+	for i, tt := range tests {
+		info, _ := reply[i].(protocol.Array)
+		if len(info) != 10 {
+			t.Fatalf("COMMAND INFO %s = %v, want ten fields", tt.name, info)
+		}
+		if info[0] != protocol.BulkString(tt.name) || info[1] != tt.arity {
+			t.Errorf("COMMAND INFO %s name and arity = %v %v, want %s %d", tt.name, info[0], info[1], tt.name, tt.arity)
+		}
+		flags, _ := info[2].(protocol.Array)
+		found := false
+		for _, flag := range flags {
+			found = found || flag == tt.flag
+		}
+		if !found {
+			t.Errorf("COMMAND INFO %s flags = %v, want %s", tt.name, flags, tt.flag)
+		}
+		if !reflect.DeepEqual(info[3:6], tt.keySpec) {
+			t.Errorf("COMMAND INFO %s key spec = %v, want %v", tt.name, info[3:6], tt.keySpec)
+		}
+	}
+	if reply[4] != nil {
+		t.Errorf("COMMAND INFO nosuchcommand = %v, want nil", reply[4])
+	}
+}
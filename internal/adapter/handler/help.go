@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+)
+
+var helpText = map[command.Type][]string{
+	command.CLUSTER: {
+		"INFO",
+		"    Return information about the cluster. Always reports cluster_enabled:0.",
+		"SLOTS",
+		"    Return the slot ranges served by this node. Always empty.",
+	},
+	command.COMMAND: {
+		"GETKEYS <full-command>",
+		"    Return the keys from a full command.",
+		"INFO [<command-name> ...]",
+		"    Return details about the given commands.",
+	},
+	command.SLOWLOG: {
+		"GET [<count>]",
+		"    Return top <count> entries from the slowlog (default: 10, -1 means all).",
+		"LEN",
+		"    Return the length of the slowlog.",
+		"RESET",
+		"    Reset the slowlog.",
+	},
+	command.CONFIG: {
+		"GET <pattern>",
+		"    Return parameters matching the glob-like <pattern> and their values.",
+		"RESETSTAT",
+		"    Reset statistics reported by the INFO command.",
+		"SET <directive> <value>",
+		"    Set the configuration <directive> to <value>.",
+	},
+	command.DEBUG: {
+		"OBJECT <key>",
+		"    Show low level info about the key and associated value.",
+		"RELOAD",
+		"    Dump the dataset and load it back to verify the persistence round trip.",
+		"SET-ACTIVE-EXPIRE <0|1>",
+		"    Setting it to 0 disables expiring keys in background when they are not accessed.",
+		"SLEEP <seconds>",
+		"    Stop the server for <seconds>. Decimals allowed.",
+	},
+	command.LATENCY: {
+		"DOCTOR",
+		"    Return a human readable latency analysis report.",
+		"HISTOGRAM [<command> ...]",
+		"    Return a cumulative distribution of latencies in the format of a histogram for the specified command names.",
+		"HISTORY <event>",
+		"    Return time-latency samples for the <event> class. Always empty.",
+		"LATEST",
+		"    Return the latest latency samples for all events. Always empty.",
+		"RESET [<event> ...]",
+		"    Reset latency data of one or more <event> classes. Always resets nothing.",
+	},
+	command.MEMORY: {
+		"PURGE",
+		"    Rebuild the keyspace map to release memory held by deleted keys.",
+		"USAGE <key> [SAMPLES <count>]",
+		"    Return memory in bytes used by <key> and its value.",
+	},
+	command.OBJECT: {
+		"ENCODING <key>",
+		"    Return the kind of internal representation used in order to store the value",
+		"    associated with a <key>.",
+		"FREQ <key>",
+		"    Return the access frequency index of the key <key>.",
+		"IDLETIME <key>",
+		"    Return the idle time of the key <key>.",
+		"REFCOUNT <key>",
+		"    Return the number of references of the value associated with the specified <key>.",
+	},
+}
+
+func help(cmdType command.Type) []string {
+	lines := []string{fmt.Sprintf("%s <subcommand> [<arg> [value] [opt] ...]. Subcommands are:", cmdType)}
+	lines = append(lines, helpText[cmdType]...)
+	return append(lines, "HELP", "    Print this help.")
+}
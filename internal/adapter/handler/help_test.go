@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHelpSubcommand(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{EnableDebugCommand: true})
+	for cmdType := range helpText {
+		name := cmdType.String()
+		t.Run(name, func(t *testing.T) {
+			lines, ok := dispatch(t, d, name, "help").([]string)
+			if !ok || len(lines) < 3 {
+				t.Fatalf("%s HELP = %v, want usage lines", name, lines)
+			}
+			if !strings.HasPrefix(lines[0], name+" <subcommand>") {
+				t.Errorf("first line = %q, want the %s usage header", lines[0], name)
+			}
+			if lines[len(lines)-2] != "HELP" {
+				t.Errorf("%s HELP does not describe HELP itself", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+)
+
+const redactedArg = "********"
+
+type clientAddrKey struct{}
+
+// defaultRedactedCommands name commands, optionally followed by a subcommand,
+// whose arguments are masked in the command log.
+var defaultRedactedCommands = []string{"CONFIG SET"}
+
+type LoggingOption struct {
+	RedactCommands []string
+}
+
+func WithClientAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, addr)
+}
+
+func ClientAddr(ctx context.Context) string {
+	addr, _ := ctx.Value(clientAddrKey{}).(string)
+	return addr
+}
+
+// Logging logs every command with its client address and duration. An entry
+// in RedactCommands such as "CONFIG SET" masks the arguments after that
+// subcommand; a bare command name masks all of its arguments.
+func Logging(logger *log.Logger, opt LoggingOption) Interceptor {
+	redact := make(map[string][]string)
+	for _, name := range append(defaultRedactedCommands, opt.RedactCommands...) {
+		fields := strings.Fields(strings.ToUpper(name))
+		switch len(fields) {
+		case 1:
+			redact[fields[0]] = append(redact[fields[0]], "")
+		case 2:
+			redact[fields[0]] = append(redact[fields[0]], fields[1])
+		}
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, cmd *protocol.Command) any {
+			start := time.Now()
+			result := next(ctx, cmd)
+			args := redactArgs(cmd.Args, redact[cmd.Type.String()])
+			logger.Printf("[%s] %s %s (%s)", ClientAddr(ctx), cmd.Type, strings.Join(args, " "), time.Since(start))
+			return result
+		}
+	}
+}
+
+func redactArgs(args []string, subcommands []string) []string {
+	for _, subcommand := range subcommands {
+		keep := 0
+		if subcommand != "" {
+			if len(args) == 0 || !strings.EqualFold(args[0], subcommand) {
+				continue
+			}
+			keep = 1
+		}
+		masked := make([]string, len(args))
+		copy(masked, args[:keep])
+		for i := keep; i < len(masked); i++ {
+			masked[i] = redactedArg
+		}
+		return masked
+	}
+	return args
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestLoggingRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{})
+	d.Use(Logging(log.New(&buf, "", 0), LoggingOption{RedactCommands: []string{"set"}}))
+
+	dispatch(t, d, "CONFIG", "SET", "maxmemory", "100")
+	dispatch(t, d, "CONFIG", "GET", "maxmemory")
+	dispatch(t, d, "SET", "k", "secret")
+	dispatch(t, d, "GET", "k")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("logged %d lines, want 4:\n%s", len(lines), buf.String())
+	}
+	want := []string{
+		"CONFIG SET ******** ********",
+		"CONFIG GET maxmemory",
+		"SET ******** ********",
+		"GET k",
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i]) {
+			t.Errorf("line %d = %q, want it to contain %q", i, line, want[i])
+		}
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Error("redacted value reached the log")
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+)
+
+const (
+	defaultSlowlogMaxLen = 128
+	slowlogMaxArgs       = 32
+	slowlogMaxArgLen     = 128
+)
+
+type SlowLogEntry struct {
+	ID        int64
+	Timestamp int64
+	Duration  time.Duration
+	Args      []string
+}
+
+type SlowLog struct {
+	entries    []SlowLogEntry
+	nextID     int64
+	slowerThan time.Duration
+	disabled   bool
+	maxLen     int
+	mu         sync.Mutex
+}
+
+func NewSlowLog(slowerThanInMicros int64, maxLen int) *SlowLog {
+	if maxLen <= 0 {
+		maxLen = defaultSlowlogMaxLen
+	}
+	return &SlowLog{
+		slowerThan: time.Duration(slowerThanInMicros) * time.Microsecond,
+		disabled:   slowerThanInMicros < 0,
+		maxLen:     maxLen,
+	}
+}
+
+func (l *SlowLog) Interceptor() Interceptor {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, cmd *protocol.Command) any {
+			start := time.Now()
+			result := next(ctx, cmd)
+			l.record(cmd, start, time.Since(start))
+			return result
+		}
+	}
+}
+
+func (l *SlowLog) Get(count int) []SlowLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+	entries := make([]SlowLogEntry, count)
+	copy(entries, l.entries[:count])
+	return entries
+}
+
+func (l *SlowLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+func (l *SlowLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+func (l *SlowLog) record(cmd *protocol.Command, start time.Time, duration time.Duration) {
+	if l.disabled || duration < l.slowerThan {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := SlowLogEntry{
+		ID:        l.nextID,
+		Timestamp: start.Unix(),
+		Duration:  duration,
+		Args:      truncateArgs(cmd),
+	}
+	l.nextID++
+	l.entries = append([]SlowLogEntry{entry}, l.entries...)
+	if len(l.entries) > l.maxLen {
+		l.entries = l.entries[:l.maxLen]
+	}
+}
+
+func truncateArgs(cmd *protocol.Command) []string {
+	args := append([]string{cmd.Type.String()}, cmd.Args...)
+	if len(args) > slowlogMaxArgs {
+		more := len(args) - slowlogMaxArgs + 1
+		args = append(args[:slowlogMaxArgs-1], fmt.Sprintf("... (%d more arguments)", more))
+	}
+	for i, arg := range args {
+		if len(arg) > slowlogMaxArgLen {
+			args[i] = fmt.Sprintf("%s... (%d more bytes)", arg[:slowlogMaxArgLen], len(arg)-slowlogMaxArgLen)
+		}
+	}
+	return args
+}
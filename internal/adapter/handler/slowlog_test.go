@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+)
+
+func TestSlowlogCapturesSlowCommand(t *testing.T) {
+	d, _ := newTestDispatcher(t, nil, DispatcherOption{
+		EnableDebugCommand:   true,
+		SlowlogLogSlowerThan: 10000,
+	})
+	dispatch(t, d, "SET", "fast", "v")
+	dispatch(t, d, "DEBUG", "SLEEP", "0.02")
+	if n := dispatch(t, d, "SLOWLOG", "LEN"); n != 1 {
+		t.Fatalf("SLOWLOG LEN = %v, want 1", n)
+	}
+	entries := dispatch(t, d, "SLOWLOG", "GET").(protocol.Array)
+	entry := entries[0].(protocol.Array)
+	if args := entry[3]; !reflect.DeepEqual(args, []string{"DEBUG", "SLEEP", "0.02"}) {
+		t.Errorf("logged args = %v", args)
+	}
+	if micros := entry[2].(int64); micros < 20000 {
+		t.Errorf("logged duration = %dus, want at least 20000", micros)
+	}
+	dispatch(t, d, "SLOWLOG", "RESET")
+	if n := dispatch(t, d, "SLOWLOG", "LEN"); n != 0 {
+		t.Errorf("SLOWLOG LEN after RESET = %v, want 0", n)
+	}
+}
+
+func TestSlowlogTruncatesArgs(t *testing.T) {
+	l := NewSlowLog(0, 1)
+	long := strings.Repeat("x", slowlogMaxArgLen+5)
+	l.record(&protocol.Command{Type: "SET", Args: []string{"k", long}}, time.Time{}, 0)
+	l.record(&protocol.Command{Type: "GET", Args: []string{"k"}}, time.Time{}, 0)
+	entries := l.Get(-1)
+	if len(entries) != 1 || entries[0].Args[0] != "GET" {
+		t.Fatalf("entries = %v, want only the newest", entries)
+	}
+	args := truncateArgs(&protocol.Command{Type: "SET", Args: []string{"k", long}})
+	if !strings.HasSuffix(args[2], "... (5 more bytes)") {
+		t.Errorf("long argument logged as %q", args[2])
+	}
+}
@@ -1,10 +1,12 @@
 package protocol
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
 )
 
 type Command struct {
@@ -21,15 +23,18 @@ func NewParser() *Parser {
 func (p *Parser) ParseCommand(line string) (*Command, error) {
 	line = strings.TrimSpace(line)
 	if line == "" {
-		return nil, fmt.Errorf("empty command")
+		return nil, entity.ErrEmptyCommand
 	}
-	parts := strings.Fields(line)
+	return p.ParseArgs(strings.Fields(line))
+}
+
+func (p *Parser) ParseArgs(parts []string) (*Command, error) {
 	if len(parts) == 0 {
-		return nil, fmt.Errorf("empty command")
+		return nil, entity.ErrEmptyCommand
 	}
 	cmdType := command.Type(strings.ToUpper(parts[0]))
 	if !cmdType.IsValid() {
-		return nil, fmt.Errorf("unknown command: %s", parts[0])
+		return nil, fmt.Errorf("%w: %s", entity.ErrUnknownCommand, parts[0])
 	}
 	cmd := &Command{
 		Type: cmdType,
@@ -43,28 +48,72 @@ func (p *Parser) ParseCommand(line string) (*Command, error) {
 
 func (p *Parser) FormatResponse(result any) string {
 	switch v := result.(type) {
+	case nil:
+		return p.FormatNil()
+	case SimpleString:
+		return fmt.Sprintf("+%s\r\n", singleLine(string(v)))
+	case BulkString:
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), string(v))
 	case string:
-		return v
+		return p.FormatResponse(BulkString(v))
+	case []string:
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "*%d\r\n", len(v))
+		for _, s := range v {
+			sb.WriteString(p.FormatResponse(BulkString(s)))
+		}
+		return sb.String()
+	case Array:
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "*%d\r\n", len(v))
+		for _, elem := range v {
+			sb.WriteString(p.FormatResponse(elem))
+		}
+		return sb.String()
 	case int, int64:
-		return fmt.Sprintf("%d", v)
+		return fmt.Sprintf(":%d\r\n", v)
 	case bool:
 		if v {
 			return p.FormatOK()
 		}
-		return "ERR operation failed"
+		return p.FormatError(errors.New("operation failed"))
 	case error:
-		return p.FormatError(v.Error())
+		return p.FormatError(v)
 	default:
-		return fmt.Sprintf("%v", result)
+		return p.FormatResponse(BulkString(fmt.Sprintf("%v", result)))
 	}
 }
 
-func (p *Parser) FormatOK() string { return "OK" }
+func (p *Parser) FormatOK() string { return "+OK\r\n" }
 
-func (p *Parser) FormatError(msg string) string {
-	return fmt.Sprintf("ERR: %s", msg)
+func (p *Parser) FormatError(err error) string {
+	return fmt.Sprintf("-%s %s\r\n", ErrorPrefix(err), singleLine(err.Error()))
+}
+
+// singleLine replaces CR and LF with spaces, as Redis does for status and
+// error replies, which often echo client bytes and must stay one line.
+func singleLine(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
 }
 
 func (p *Parser) FormatNil() string {
-	return "nil"
+	return "$-1\r\n"
+}
+
+// ErrorPrefix returns the error class Redis uses as the first word of an
+// error reply, such as ERR or WRONGTYPE.
+func ErrorPrefix(err error) string {
+	switch {
+	case errors.Is(err, entity.ErrWrongType), errors.Is(err, entity.ErrNotHLL):
+		return "WRONGTYPE"
+	case errors.Is(err, entity.ErrOOM):
+		return "OOM"
+	case errors.Is(err, entity.ErrMisconf):
+		return "MISCONF"
+	default:
+		return "ERR"
+	}
 }
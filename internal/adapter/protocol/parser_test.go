@@ -0,0 +1,35 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+func TestFormatResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		result any
+		want   string
+	}{
+		{"nil", nil, "$-1\r\n"},
+		{"simple string", OK, "+OK\r\n"},
+		{"bulk string", BulkString("v"), "$1\r\nv\r\n"},
+		{"empty bulk string", BulkString(""), "$0\r\n\r\n"},
+		{"int", 3, ":3\r\n"},
+		{"int64", int64(-2), ":-2\r\n"},
+		{"string slice", []string{"a", "bc"}, "*2\r\n$1\r\na\r\n$2\r\nbc\r\n"},
+		{"nested array", Array{1, nil, Array{}}, "*3\r\n:1\r\n$-1\r\n*0\r\n"},
+		{"error", errors.New("boom"), "-ERR boom\r\n"},
+		{"wrong type", entity.ErrWrongType, "-WRONGTYPE " + entity.ErrWrongType.Error() + "\r\n"},
+		{"error with line breaks", errors.New("unknown command 'a\r\n+OK'"), "-ERR unknown command 'a  +OK'\r\n"},
+		{"simple string with line breaks", SimpleString("a\nb\rc"), "+a b c\r\n"},
+	}
+	p := NewParser()
+	for _, tt := range tests {
+		if got := p.FormatResponse(tt.result); got != tt.want {
+			t.Errorf("%s: FormatResponse = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultMaxInlineLen = 64 * 1024
+	DefaultMaxBulkLen   = 512 * 1024 * 1024
+	maxMultibulkLen     = 1024 * 1024
+	// A length header alone never allocates more than these; longer
+	// requests grow their buffers as the data arrives.
+	maxPreallocArgs  = 1024
+	maxPreallocBytes = 64 * 1024
+)
+
+var (
+	ErrProtocol            = errors.New("Protocol error")
+	ErrInlineTooBig        = fmt.Errorf("%w: too big inline request", ErrProtocol)
+	ErrInvalidMultibulkLen = fmt.Errorf("%w: invalid multibulk length", ErrProtocol)
+	ErrInvalidBulkLen      = fmt.Errorf("%w: invalid bulk length", ErrProtocol)
+)
+
+type ReaderOption struct {
+	MaxInlineLen int
+	MaxBulkLen   int
+}
+
+type Reader struct {
+	r      *bufio.Reader
+	opt    ReaderOption
+	inline bool
+}
+
+func NewReader(r io.Reader, opt ReaderOption) *Reader {
+	if opt.MaxInlineLen <= 0 {
+		opt.MaxInlineLen = DefaultMaxInlineLen
+	}
+	if opt.MaxBulkLen <= 0 {
+		opt.MaxBulkLen = DefaultMaxBulkLen
+	}
+	return &Reader{
+		r:   bufio.NewReader(r),
+		opt: opt,
+	}
+}
+
+// ReadCommand returns the next request. Blank inline lines are skipped, as
+// telnet users often send a stray newline between commands.
+func (r *Reader) ReadCommand() ([]string, error) {
+	line, err := r.readLine()
+	for err == nil && strings.TrimSpace(line) == "" {
+		line, err = r.readLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.inline = !strings.HasPrefix(line, "*")
+	if r.inline {
+		return strings.Fields(line), nil
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || count > maxMultibulkLen {
+		return nil, ErrInvalidMultibulkLen
+	}
+	if count <= 0 {
+		return []string{}, nil
+	}
+	args := make([]string, 0, min(count, maxPreallocArgs))
+	for i := 0; i < count; i++ {
+		arg, err := r.readBulk()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// Inline reports whether the last request was an inline command, whose
+// arguments were split on whitespace rather than length-prefixed.
+func (r *Reader) Inline() bool {
+	return r.inline
+}
+
+func (r *Reader) readBulk() (string, error) {
+	header, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(header, "$") {
+		return "", fmt.Errorf("%w: expected '$', got '%s'", ErrProtocol, strings.TrimSpace(header))
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	if err != nil || n < 0 || n > r.opt.MaxBulkLen {
+		return "", ErrInvalidBulkLen
+	}
+	var buf []byte
+	if n+2 <= maxPreallocBytes {
+		buf = make([]byte, n+2)
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			return "", err
+		}
+	} else {
+		var b bytes.Buffer
+		b.Grow(maxPreallocBytes)
+		if _, err := io.CopyN(&b, r.r, int64(n+2)); err != nil {
+			return "", err
+		}
+		buf = b.Bytes()
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string not terminated by CRLF", ErrProtocol)
+	}
+	return string(buf[:n]), nil
+}
+
+// readLine returns the next line including its terminator. Lines end at '\n'
+// with an optional '\r' before it, so both CRLF and bare LF clients work.
+func (r *Reader) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		if len(line)+len(chunk) > r.opt.MaxInlineLen {
+			return "", ErrInlineTooBig
+		}
+		line = append(line, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(line), nil
+	}
+}
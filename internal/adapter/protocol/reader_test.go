@@ -0,0 +1,101 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+	input := "*2\r\n$4\r\nECHO\r\n$5\r\nhello\r\n\r\nPING now\n*0\r\n"
+	r := NewReader(strings.NewReader(input), ReaderOption{})
+	want := [][]string{{"ECHO", "hello"}, {"PING", "now"}, {}}
+	inline := []bool{false, true, false}
+	for i, w := range want {
+		args, err := r.ReadCommand()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(args, w) {
+			t.Errorf("ReadCommand = %q, want %q", args, w)
+		}
+		if r.Inline() != inline[i] {
+			t.Errorf("Inline after %q = %v, want %v", args, r.Inline(), inline[i])
+		}
+	}
+}
+
+func TestReadCommandLineEndings(t *testing.T) {
+	for _, input := range []string{
+		"SET k v\r\nGET k\r\n",
+		"SET k v\nGET k\n",
+		"SET k v\r\nGET k\n",
+		"\nSET k v\n\r\nGET k\n",
+	} {
+		r := NewReader(strings.NewReader(input), ReaderOption{})
+		for _, want := range [][]string{{"SET", "k", "v"}, {"GET", "k"}} {
+			args, err := r.ReadCommand()
+			if err != nil {
+				t.Fatalf("%q: %v", input, err)
+			}
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("%q: ReadCommand = %q, want %q", input, args, want)
+			}
+		}
+	}
+}
+
+func TestReadCommandErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opt   ReaderOption
+		want  error
+	}{
+		{"multibulk count too large", "*1048577\r\n", ReaderOption{}, ErrInvalidMultibulkLen},
+		{"multibulk count not a number", "*x\r\n", ReaderOption{}, ErrInvalidMultibulkLen},
+		{"negative bulk length", "*1\r\n$-2\r\n", ReaderOption{}, ErrInvalidBulkLen},
+		{"bulk over max-bulk-len", "*1\r\n$11\r\nhello world\r\n", ReaderOption{MaxBulkLen: 10}, ErrInvalidBulkLen},
+		{"inline over max-inline-len", strings.Repeat("x", 20) + "\r\n", ReaderOption{MaxInlineLen: 10}, ErrInlineTooBig},
+		{"bulk without CRLF", "*1\r\n$2\r\nabcd", ReaderOption{}, ErrProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tt.input), tt.opt).ReadCommand()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCommandDoesNotTrustHeaders(t *testing.T) {
+	// Declared sizes followed by EOF must fail without allocating what the
+	// headers promised.
+	for _, input := range []string{"*1048576\r\n", "*1\r\n$536870912\r\n"} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := NewReader(strings.NewReader(input), ReaderOption{}).ReadCommand(); err == nil {
+			t.Fatalf("%q: truncated request parsed", input)
+		}
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%q: allocated %d bytes", input, allocated)
+		}
+	}
+}
+
+func TestReadLargeBulk(t *testing.T) {
+	value := strings.Repeat("v", 3*maxPreallocBytes)
+	input := "*1\r\n$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+	args, err := NewReader(strings.NewReader(input), ReaderOption{}).ReadCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != value {
+		t.Error("large bulk not read back intact")
+	}
+}
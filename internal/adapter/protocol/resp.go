@@ -0,0 +1,9 @@
+package protocol
+
+type SimpleString string
+
+type BulkString string
+
+type Array []any
+
+const OK = SimpleString("OK")
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/handler"
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+)
+
+const defaultOutputBufferSize = 16 * 1024
+
+type ServerOption struct {
+	Addr             string
+	UnixSocket       string
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSCACertFile    string
+	TLSAuthClients   bool
+	MaxInlineLen     int
+	MaxBulkLen       int
+	OutputBufferSize int
+}
+
+type Server struct {
+	opt        ServerOption
+	dispatcher *handler.Dispatcher
+	pubsub     repository.PubSubRepository
+	parser     *protocol.Parser
+	listeners  []net.Listener
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+}
+
+func NewServer(dispatcher *handler.Dispatcher, pubsub repository.PubSubRepository, opt ServerOption) *Server {
+	return &Server{
+		opt:        opt,
+		dispatcher: dispatcher,
+		pubsub:     pubsub,
+		parser:     protocol.NewParser(),
+	}
+}
+
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if s.opt.Addr == "" && s.opt.UnixSocket == "" {
+		return fmt.Errorf("no listen address or unix socket configured")
+	}
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	if s.opt.Addr != "" {
+		if err := s.listen("tcp", s.opt.Addr, tlsConfig); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	if s.opt.UnixSocket != "" {
+		if err := os.Remove(s.opt.UnixSocket); err != nil && !os.IsNotExist(err) {
+			s.Close()
+			return err
+		}
+		if err := s.listen("unix", s.opt.UnixSocket, nil); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	s.mu.Lock()
+	for _, ln := range s.listeners {
+		s.wg.Add(1)
+		go s.acceptLoop(ctx, ln)
+	}
+	s.mu.Unlock()
+	<-ctx.Done()
+	err = s.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addrs := make([]net.Addr, len(s.listeners))
+	for i, ln := range s.listeners {
+		addrs[i] = ln.Addr()
+	}
+	return addrs
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, ln := range s.listeners {
+		if err := ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	s.listeners = nil
+	return errors.Join(errs...)
+}
+
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if s.opt.TLSCertFile == "" && s.opt.TLSKeyFile == "" {
+		if s.opt.TLSCACertFile != "" || s.opt.TLSAuthClients {
+			return nil, fmt.Errorf("TLS client verification requires tls-cert-file and tls-key-file")
+		}
+		return nil, nil
+	}
+	if s.opt.TLSCertFile == "" || s.opt.TLSKeyFile == "" {
+		return nil, fmt.Errorf("tls-cert-file and tls-key-file must be provided together")
+	}
+	cert, err := tls.LoadX509KeyPair(s.opt.TLSCertFile, s.opt.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if s.opt.TLSCACertFile != "" {
+		pem, err := os.ReadFile(s.opt.TLSCACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", s.opt.TLSCACertFile)
+		}
+		config.ClientCAs = pool
+	}
+	if s.opt.TLSAuthClients {
+		if config.ClientCAs == nil {
+			return nil, fmt.Errorf("tls-auth-clients requires tls-ca-cert-file")
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+func (s *Server) listen(network, address string, tlsConfig *tls.Config) error {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, ln)
+	return nil
+}
+
+func (s *Server) acceptLoop(ctx context.Context, ln net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
+	ctx, cancel := context.WithCancel(handler.WithClientAddr(ctx, conn.RemoteAddr().String()))
+	defer cancel()
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	bufferSize := s.opt.OutputBufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultOutputBufferSize
+	}
+	sess := newSession(conn, s.parser, s.pubsub, bufferSize)
+	defer sess.close()
+	reader := protocol.NewReader(flushingReader{conn: conn, sess: sess}, protocol.ReaderOption{
+		MaxInlineLen: s.opt.MaxInlineLen,
+		MaxBulkLen:   s.opt.MaxBulkLen,
+	})
+	for {
+		args, err := reader.ReadCommand()
+		if errors.Is(err, protocol.ErrProtocol) {
+			sess.write(s.parser.FormatError(err))
+			sess.flush()
+			return
+		}
+		if err != nil {
+			return
+		}
+		response, quit := s.execute(ctx, sess, args)
+		if err := sess.write(response); err != nil {
+			return
+		}
+		if quit {
+			sess.flush()
+			return
+		}
+	}
+}
+
+// flushingReader flushes buffered replies whenever the request reader has to
+// go back to the connection for more input. That is the only point where it
+// can block, so pipelined replies are batched while requests keep arriving,
+// and never held back behind a half-received one.
+type flushingReader struct {
+	conn net.Conn
+	sess *session
+}
+
+func (r flushingReader) Read(p []byte) (int, error) {
+	if err := r.sess.flush(); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(p)
+}
+
+// execute runs one request and reports whether the connection should be
+// closed once the reply has been written.
+func (s *Server) execute(ctx context.Context, sess *session, args []string) (string, bool) {
+	cmd, err := s.parser.ParseArgs(args)
+	if err != nil {
+		s.dispatcher.RecordError(err)
+		return s.parser.FormatError(err), false
+	}
+	return s.run(ctx, sess, cmd), cmd.Type == command.QUIT
+}
+
+func (s *Server) run(ctx context.Context, sess *session, cmd *protocol.Command) string {
+	if sess.subscribing() {
+		switch cmd.Type {
+		case command.SUBSCRIBE, command.UNSUBSCRIBE, command.QUIT:
+		case command.PING:
+			return sess.ping(cmd)
+		default:
+			return s.parser.FormatError(fmt.Errorf("Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context",
+				strings.ToLower(cmd.Type.String())))
+		}
+	}
+	switch cmd.Type {
+	case command.SUBSCRIBE:
+		return sess.subscribe(ctx, cmd)
+	case command.UNSUBSCRIBE:
+		return sess.unsubscribe(ctx, cmd)
+	default:
+		return s.parser.FormatResponse(s.dispatcher.Dispatch(ctx, cmd))
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/handler"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/pubsub"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/storage"
+)
+
+// countingConn counts the writes the server issues, one per flush.
+type countingConn struct {
+	net.Conn
+	writes atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(p)
+}
+
+func newTestServer(opt ServerOption) *Server {
+	store := storage.NewStore(storage.StoreOption{})
+	broker := pubsub.NewBroker()
+	return NewServer(handler.NewDispatcher(store, nil, broker, handler.DispatcherOption{}), broker, opt)
+}
+
+func newTestConn(t testing.TB, opt ServerOption) (net.Conn, *countingConn) {
+	t.Helper()
+	return connect(t, newTestServer(opt))
+}
+
+// connect serves one end of an in-memory pipe with srv until the test ends
+// and returns the other end.
+func connect(t testing.TB, srv *Server) (net.Conn, *countingConn) {
+	t.Helper()
+	client, server := net.Pipe()
+	conn := &countingConn{Conn: server}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.handleConn(ctx, conn)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+		<-done
+	})
+	return client, conn
+}
+
+func TestReplyFlushedBeforePartialRequest(t *testing.T) {
+	client, _ := newTestConn(t, ServerOption{})
+	go client.Write([]byte("PING\r\n*1\r\n$4\r\nPI"))
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(client).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reply to PING held back behind a partial request: %v", err)
+	}
+	if line != "+PONG\r\n" {
+		t.Errorf("reply = %q, want +PONG", line)
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	const depth = 100
+	client, conn := newTestConn(b, ServerOption{})
+	request := []byte(strings.Repeat("*1\r\n$4\r\nPING\r\n", depth))
+	reply := len("+PONG\r\n") * depth
+	go func() {
+		for i := 0; i < b.N; i++ {
+			client.Write(request)
+		}
+	}()
+	buf := make([]byte, reply)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := io.ReadFull(client, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(conn.writes.Load())/float64(b.N*depth), "writes/cmd")
+}
+
+// startServer runs a server with opt until the test ends and returns it once
+// all of its listeners are accepting.
+func startServer(t *testing.T, opt ServerOption) *Server {
+	t.Helper()
+	srv := newTestServer(opt)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	want := 0
+	if opt.Addr != "" {
+		want++
+	}
+	if opt.UnixSocket != "" {
+		want++
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(srv.Addrs()) < want {
+		select {
+		case err := <-done:
+			t.Fatalf("ListenAndServe: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server did not start listening")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return srv
+}
+
+// roundTrip sends SET k v and GET k over conn and checks both replies.
+func roundTrip(t *testing.T, conn net.Conn) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Write([]byte("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	want := "+OK\r\n$1\r\nv\r\n"
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("replies = %q, want %q", got, want)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	srv := startServer(t, ServerOption{Addr: "127.0.0.1:0", UnixSocket: path})
+	if n := len(srv.Addrs()); n != 2 {
+		t.Fatalf("listening on %d addresses, want tcp and unix", n)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+}
+
+func TestOversizedInlineRequestClosesConnection(t *testing.T) {
+	client, _ := newTestConn(t, ServerOption{MaxInlineLen: 1024})
+	go client.Write([]byte(strings.Repeat("x", 4096)))
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("connection not closed after an oversized request: %v", err)
+	}
+	if want := "-ERR Protocol error: too big inline request\r\n"; string(reply) != want {
+		t.Errorf("reply = %q, want %q", reply, want)
+	}
+}
+
+func TestQuitRepliesThenCloses(t *testing.T) {
+	client, _ := newTestConn(t, ServerOption{})
+	go client.Write([]byte("QUIT\r\nPING\r\n"))
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("connection not closed after QUIT: %v", err)
+	}
+	if string(reply) != "+OK\r\n" {
+		t.Errorf("reply = %q, want only +OK", reply)
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+)
+
+const sessionMessageBuffer = 1024
+
+// session holds per-connection state. All output goes through writeMu, and
+// messages from every subscribed channel are funnelled through the single
+// messages channel into one forward goroutine, so frames never interleave and
+// each channel's messages arrive in publish order.
+type session struct {
+	conn       net.Conn
+	out        *bufio.Writer
+	parser     *protocol.Parser
+	pubsub     repository.PubSubRepository
+	channels   map[string]struct{}
+	messages   chan entity.Message
+	done       chan struct{}
+	forwarding bool
+	writeMu    sync.Mutex
+}
+
+func newSession(conn net.Conn, parser *protocol.Parser, pubsub repository.PubSubRepository, outputBufferSize int) *session {
+	return &session{
+		conn:     conn,
+		out:      bufio.NewWriterSize(conn, outputBufferSize),
+		parser:   parser,
+		pubsub:   pubsub,
+		channels: make(map[string]struct{}),
+		messages: make(chan entity.Message, sessionMessageBuffer),
+		done:     make(chan struct{}),
+	}
+}
+
+// write buffers a reply; it reaches the client once the buffer fills or
+// flush is called.
+func (s *session) write(response string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.out.WriteString(response)
+	return err
+}
+
+// push writes and flushes a frame in one critical section, so a published
+// message can never land between a reply's bytes or ahead of buffered replies.
+func (s *session) push(frame string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.out.WriteString(frame); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func (s *session) flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.out.Flush()
+}
+
+func (s *session) subscribe(ctx context.Context, cmd *protocol.Command) string {
+	if len(cmd.Args) == 0 {
+		return s.parser.FormatError(wrongArgs(cmd))
+	}
+	var sb strings.Builder
+	for _, channel := range cmd.Args {
+		if _, exists := s.channels[channel]; !exists {
+			s.channels[channel] = struct{}{}
+			s.pubsub.Subscribe(ctx, channel, s.messages)
+		}
+		sb.WriteString(s.reply("subscribe", channel))
+	}
+	if !s.forwarding {
+		s.forwarding = true
+		go s.forward()
+	}
+	return sb.String()
+}
+
+func (s *session) unsubscribe(ctx context.Context, cmd *protocol.Command) string {
+	channels := cmd.Args
+	if len(channels) == 0 {
+		channels = s.subscribed()
+	}
+	if len(channels) == 0 {
+		return s.parser.FormatResponse(protocol.Array{protocol.BulkString("unsubscribe"), nil, 0})
+	}
+	var sb strings.Builder
+	for _, channel := range channels {
+		if _, exists := s.channels[channel]; exists {
+			delete(s.channels, channel)
+			s.pubsub.Unsubscribe(ctx, channel, s.messages)
+		}
+		sb.WriteString(s.reply("unsubscribe", channel))
+	}
+	return sb.String()
+}
+
+func (s *session) subscribing() bool {
+	return len(s.channels) > 0
+}
+
+// ping replies with the pub/sub frame clients expect while subscribed.
+func (s *session) ping(cmd *protocol.Command) string {
+	switch len(cmd.Args) {
+	case 0:
+		return s.parser.FormatResponse(protocol.Array{protocol.BulkString("pong"), protocol.BulkString("")})
+	case 1:
+		return s.parser.FormatResponse(protocol.Array{protocol.BulkString("pong"), protocol.BulkString(cmd.Args[0])})
+	default:
+		return s.parser.FormatError(wrongArgs(cmd))
+	}
+}
+
+func (s *session) close() {
+	if s.forwarding {
+		s.pubsub.UnsubscribeAll(context.Background(), s.messages)
+	}
+	close(s.done)
+}
+
+func (s *session) subscribed() []string {
+	channels := make([]string, 0, len(s.channels))
+	for channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+func (s *session) reply(kind, channel string) string {
+	return s.parser.FormatResponse(protocol.Array{
+		protocol.BulkString(kind),
+		protocol.BulkString(channel),
+		len(s.channels),
+	})
+}
+
+func (s *session) forward() {
+	for {
+		select {
+		case msg, ok := <-s.messages:
+			if !ok {
+				// The broker dropped this client for falling behind.
+				s.conn.Close()
+				return
+			}
+			s.push(s.parser.FormatResponse(protocol.Array{
+				protocol.BulkString("message"),
+				protocol.BulkString(msg.Channel),
+				protocol.BulkString(msg.Payload),
+			}))
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func wrongArgs(cmd *protocol.Command) error {
+	return fmt.Errorf("%w for '%s' command", entity.ErrWrongArgs, strings.ToLower(cmd.Type.String()))
+}
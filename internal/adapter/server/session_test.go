@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exchange writes request to conn and checks that the next bytes it sends
+// back are exactly want.
+func exchange(t *testing.T, conn net.Conn, request, want string) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	go conn.Write([]byte(request))
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("reply to %q: %v (got %q)", request, err, got)
+	}
+	if string(got) != want {
+		t.Errorf("reply to %q = %q, want %q", request, got, want)
+	}
+}
+
+func TestSubscribeReplyFrames(t *testing.T) {
+	client, _ := newTestConn(t, ServerOption{})
+	exchange(t, client, "SUBSCRIBE b a b\r\n",
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:1\r\n"+
+			"*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:2\r\n"+
+			"*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n")
+	exchange(t, client, "UNSUBSCRIBE\r\n",
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:1\r\n"+
+			"*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:0\r\n")
+	exchange(t, client, "UNSUBSCRIBE\r\n", "*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n")
+}
+
+func TestCommandsRestrictedWhileSubscribed(t *testing.T) {
+	client, _ := newTestConn(t, ServerOption{})
+	exchange(t, client, "SUBSCRIBE news\r\n", "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n")
+	exchange(t, client, "SET k v\r\n",
+		"-ERR Can't execute 'set': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context\r\n")
+	exchange(t, client, "PING\r\n", "*2\r\n$4\r\npong\r\n$0\r\n\r\n")
+	exchange(t, client, "UNSUBSCRIBE news\r\n", "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:0\r\n")
+	exchange(t, client, "SET k v\r\n", "+OK\r\n")
+}
+
+// readFrame reads one RESP array of bulk strings, integers or nils and fails
+// the test on anything malformed.
+func readFrame(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if !strings.HasPrefix(line, "*") || !strings.HasSuffix(line, "\r\n") {
+		t.Fatalf("frame header = %q, want an array", line)
+	}
+	n, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil {
+		t.Fatalf("frame header = %q: %v", line, err)
+	}
+	frame := make([]string, n)
+	for i := range frame {
+		header, err := r.ReadString('\n')
+		if err != nil || !strings.HasSuffix(header, "\r\n") {
+			t.Fatalf("element header = %q: %v", header, err)
+		}
+		switch header[0] {
+		case ':':
+			frame[i] = header[1 : len(header)-2]
+		case '$':
+			size, err := strconv.Atoi(header[1 : len(header)-2])
+			if err != nil {
+				t.Fatalf("bulk header = %q", header)
+			}
+			if size < 0 {
+				continue
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil || string(data[size:]) != "\r\n" {
+				t.Fatalf("bulk %q not terminated by CRLF: %v", data, err)
+			}
+			frame[i] = string(data[:size])
+		default:
+			t.Fatalf("element header = %q", header)
+		}
+	}
+	return frame
+}
+
+func TestConcurrentPublishFramesAreWellFormed(t *testing.T) {
+	const perChannel, pings = 200, 100
+	channels := []string{"a", "b", "c", "d"}
+	srv := newTestServer(ServerOption{})
+	client, _ := connect(t, srv)
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(client)
+	go client.Write([]byte("SUBSCRIBE " + strings.Join(channels, " ") + "\r\n"))
+	for range channels {
+		if frame := readFrame(t, r); frame[0] != "subscribe" {
+			t.Fatalf("frame = %q, want a subscribe reply", frame)
+		}
+	}
+
+	ctx := context.Background()
+	for _, channel := range channels {
+		go func() {
+			for i := 0; i < perChannel; i++ {
+				srv.pubsub.Publish(ctx, channel, channel+":"+strconv.Itoa(i))
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < pings; i++ {
+			client.Write([]byte("PING\r\n"))
+		}
+	}()
+
+	next := make(map[string]int)
+	pongs := 0
+	for received := 0; received < len(channels)*perChannel+pings; received++ {
+		frame := readFrame(t, r)
+		switch {
+		case len(frame) == 2 && frame[0] == "pong":
+			pongs++
+		case len(frame) == 3 && frame[0] == "message":
+			channel := frame[1]
+			if want := channel + ":" + strconv.Itoa(next[channel]); frame[2] != want {
+				t.Fatalf("message on %s = %q, want %q", channel, frame[2], want)
+			}
+			next[channel]++
+		default:
+			t.Fatalf("unexpected frame %q", frame)
+		}
+	}
+	if pongs != pings {
+		t.Errorf("got %d pongs, want %d", pongs, pings)
+	}
+}
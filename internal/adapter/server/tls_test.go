@@ -0,0 +1,98 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key to dir
+// and returns their paths and the parsed certificate.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "redis-like-golang test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+	srv := startServer(t, ServerOption{Addr: "127.0.0.1:0", TLSCertFile: certFile, TLSKeyFile: keyFile})
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	config := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	conn, err := tls.Dial("tcp", srv.Addrs()[0].String(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+}
+
+func TestTLSOptionValidation(t *testing.T) {
+	certFile, keyFile, _ := writeSelfSignedCert(t, t.TempDir())
+	tests := []struct {
+		name string
+		opt  ServerOption
+	}{
+		{"cert without key", ServerOption{TLSCertFile: certFile}},
+		{"key without cert", ServerOption{TLSKeyFile: keyFile}},
+		{"client auth without cert", ServerOption{TLSAuthClients: true}},
+		{"client auth without ca", ServerOption{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSAuthClients: true}},
+		{"mismatched pair", ServerOption{TLSCertFile: keyFile, TLSKeyFile: certFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewServer(nil, nil, tt.opt).tlsConfig(); err == nil {
+				t.Error("tlsConfig accepted an invalid TLS setup")
+			}
+		})
+	}
+	if _, err := NewServer(nil, nil, ServerOption{TLSCertFile: certFile, TLSKeyFile: keyFile}).tlsConfig(); err != nil {
+		t.Errorf("valid cert and key rejected: %v", err)
+	}
+}
@@ -1,5 +1,10 @@
 package command
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Type string
 
 const (
@@ -7,23 +12,85 @@ const (
 	GET     Type = "GET"
 	DEL     Type = "DEL"
 	EXPIRE  Type = "EXPIRE"
+	PEXPIRE Type = "PEXPIRE"
 	TTL     Type = "TTL"
+	PTTL    Type = "PTTL"
 	PERSIST Type = "PERSIST"
 	QUIT    Type = "QUIT"
 
 	KEYS   Type = "KEYS"
+	SCAN   Type = "SCAN"
 	EXISTS Type = "EXISTS"
 	PING   Type = "PING"
 	INFO   Type = "INFO"
+
+	COMMAND  Type = "COMMAND"
+	SLOWLOG  Type = "SLOWLOG"
+	DEBUG    Type = "DEBUG"
+	OBJECT   Type = "OBJECT"
+	TIME     Type = "TIME"
+	LASTSAVE Type = "LASTSAVE"
+	CLUSTER  Type = "CLUSTER"
+
+	PFADD   Type = "PFADD"
+	PFCOUNT Type = "PFCOUNT"
+	PFMERGE Type = "PFMERGE"
+
+	CAS Type = "CAS"
+
+	BITPOS Type = "BITPOS"
+	SETEX  Type = "SETEX"
+	PSETEX Type = "PSETEX"
+
+	SUBSCRIBE   Type = "SUBSCRIBE"
+	UNSUBSCRIBE Type = "UNSUBSCRIBE"
+	PUBLISH     Type = "PUBLISH"
+
+	FLUSHALL Type = "FLUSHALL"
+	FLUSHDB  Type = "FLUSHDB"
+	MEMORY   Type = "MEMORY"
+	CONFIG   Type = "CONFIG"
+	WAITAOF  Type = "WAITAOF"
+	LOLWUT   Type = "LOLWUT"
+	BGSAVE   Type = "BGSAVE"
+	FAILOVER Type = "FAILOVER"
+	LATENCY  Type = "LATENCY"
 )
 
+type KeySpec struct {
+	FirstKey int
+	LastKey  int
+	Step     int
+}
+
+var keySpecs = map[Type]KeySpec{
+	SET:     {FirstKey: 1, LastKey: 1, Step: 1},
+	GET:     {FirstKey: 1, LastKey: 1, Step: 1},
+	DEL:     {FirstKey: 1, LastKey: 1, Step: 1},
+	EXPIRE:  {FirstKey: 1, LastKey: 1, Step: 1},
+	PEXPIRE: {FirstKey: 1, LastKey: 1, Step: 1},
+	TTL:     {FirstKey: 1, LastKey: 1, Step: 1},
+	PTTL:    {FirstKey: 1, LastKey: 1, Step: 1},
+	PERSIST: {FirstKey: 1, LastKey: 1, Step: 1},
+	EXISTS:  {FirstKey: 1, LastKey: 1, Step: 1},
+	PFADD:   {FirstKey: 1, LastKey: 1, Step: 1},
+	PFCOUNT: {FirstKey: 1, LastKey: -1, Step: 1},
+	PFMERGE: {FirstKey: 1, LastKey: -1, Step: 1},
+	CAS:     {FirstKey: 1, LastKey: 1, Step: 1},
+	BITPOS:  {FirstKey: 1, LastKey: 1, Step: 1},
+	SETEX:   {FirstKey: 1, LastKey: 1, Step: 1},
+	PSETEX:  {FirstKey: 1, LastKey: 1, Step: 1},
+}
+
 func (t Type) String() string {
 	return string(t)
 }
 
 func (t Type) IsValid() bool {
 	switch t {
-	case SET, GET, DEL, EXPIRE, TTL, PERSIST, QUIT, KEYS, EXISTS, PING, INFO:
+	case SET, GET, DEL, EXPIRE, PEXPIRE, TTL, PTTL, PERSIST, QUIT, KEYS, SCAN, EXISTS, PING, INFO, COMMAND, SLOWLOG, DEBUG, OBJECT, TIME, LASTSAVE, CLUSTER,
+		PFADD, PFCOUNT, PFMERGE, CAS, BITPOS, SETEX, PSETEX, SUBSCRIBE, UNSUBSCRIBE, PUBLISH, FLUSHALL, FLUSHDB, MEMORY, CONFIG, WAITAOF, LOLWUT, BGSAVE,
+		FAILOVER, LATENCY:
 		return true
 	default:
 		return false
@@ -32,9 +99,40 @@ func (t Type) IsValid() bool {
 
 func (t Type) IsWriteCommand() bool {
 	switch t {
-	case SET, DEL, EXPIRE, PERSIST:
+	case SET, SETEX, PSETEX, DEL, EXPIRE, PEXPIRE, PERSIST, PFADD, PFMERGE, CAS, FLUSHALL, FLUSHDB:
 		return true
 	default:
 		return false
 	}
 }
+
+func (t Type) KeySpec() (KeySpec, bool) {
+	spec, exists := keySpecs[t]
+	return spec, exists
+}
+
+func GetKeys(parts []string) ([]string, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("invalid command specified")
+	}
+	cmdType := Type(strings.ToUpper(parts[0]))
+	if !cmdType.IsValid() {
+		return nil, fmt.Errorf("invalid command specified")
+	}
+	spec, exists := cmdType.KeySpec()
+	if !exists {
+		return nil, fmt.Errorf("the command has no key arguments")
+	}
+	last := spec.LastKey
+	if last < 0 {
+		last = len(parts) + last
+	}
+	if spec.FirstKey >= len(parts) || last >= len(parts) {
+		return nil, fmt.Errorf("invalid number of arguments specified for command")
+	}
+	keys := []string{}
+	for i := spec.FirstKey; i <= last; i += spec.Step {
+		keys = append(keys, parts[i])
+	}
+	return keys, nil
+}
@@ -0,0 +1,67 @@
+package command
+
+// arities follow Redis: a positive arity is the exact argument count
+// including the command name, a negative one is the minimum.
+var arities = map[Type]int{
+	SET: -3, GET: 2, DEL: 2, EXPIRE: 3, PEXPIRE: 3, TTL: 2, PTTL: 2, PERSIST: 2, QUIT: -1,
+	KEYS: 2, SCAN: -2, EXISTS: 2, PING: -1, INFO: -1,
+	COMMAND: -1, SLOWLOG: -2, DEBUG: -2, OBJECT: -2, TIME: 1, LASTSAVE: 1, CLUSTER: -2,
+	PFADD: -2, PFCOUNT: -2, PFMERGE: -2, CAS: 4,
+	BITPOS: -3, SETEX: 4, PSETEX: 4,
+	SUBSCRIBE: -2, UNSUBSCRIBE: -1, PUBLISH: 3,
+	FLUSHALL: -1, FLUSHDB: -1, MEMORY: -2, CONFIG: -2, WAITAOF: 4, LOLWUT: -1, BGSAVE: -1,
+	FAILOVER: -1, LATENCY: -2,
+}
+
+var fastCommands = map[Type]bool{
+	GET: true, DEL: true, EXPIRE: true, PEXPIRE: true, TTL: true, PTTL: true, PERSIST: true, EXISTS: true,
+	PING: true, TIME: true, LASTSAVE: true, CAS: true, SETEX: true, PSETEX: true, PUBLISH: true,
+}
+
+// dataCommands operate on the keyspace. Those that do not write are flagged
+// readonly, whether or not they name keys.
+var dataCommands = map[Type]bool{
+	SET: true, GET: true, DEL: true, EXPIRE: true, PEXPIRE: true, TTL: true, PTTL: true, PERSIST: true,
+	KEYS: true, SCAN: true, EXISTS: true, PFADD: true, PFCOUNT: true, PFMERGE: true, CAS: true,
+	BITPOS: true, SETEX: true, PSETEX: true, FLUSHALL: true, FLUSHDB: true,
+}
+
+var adminCommands = map[Type]bool{
+	SLOWLOG: true, DEBUG: true, FLUSHALL: true, FLUSHDB: true, CONFIG: true, BGSAVE: true,
+	FAILOVER: true, LATENCY: true,
+}
+
+type Info struct {
+	Name     string
+	Arity    int
+	Flags    []string
+	FirstKey int
+	LastKey  int
+	Step     int
+}
+
+// Info describes t the way COMMAND INFO does, so clients can route requests
+// and tell reads from writes.
+func (t Type) Info() Info {
+	info := Info{Name: t.String(), Arity: arities[t]}
+	spec, hasKeys := t.KeySpec()
+	if hasKeys {
+		info.FirstKey, info.LastKey, info.Step = spec.FirstKey, spec.LastKey, spec.Step
+	}
+	switch {
+	case t.IsWriteCommand():
+		info.Flags = append(info.Flags, "write")
+	case dataCommands[t]:
+		info.Flags = append(info.Flags, "readonly")
+	}
+	if adminCommands[t] {
+		info.Flags = append(info.Flags, "admin")
+	}
+	if t == SUBSCRIBE || t == UNSUBSCRIBE || t == PUBLISH {
+		info.Flags = append(info.Flags, "pubsub")
+	}
+	if fastCommands[t] {
+		info.Flags = append(info.Flags, "fast")
+	}
+	return info
+}
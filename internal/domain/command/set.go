@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+// SetExOptions builds the options for SETEX and PSETEX, whose expire comes in
+// seconds or milliseconds respectively.
+func SetExOptions(t Type, expire string) (SetOptions, error) {
+	n, err := strconv.ParseInt(expire, 10, 64)
+	if err != nil {
+		return SetOptions{}, entity.ErrNotInteger
+	}
+	ms, valid := ExpireMillis(n, t == PSETEX)
+	if !valid {
+		return SetOptions{}, fmt.Errorf("invalid expire time in '%s' command", strings.ToLower(t.String()))
+	}
+	return SetOptions{TTLMillis: ms}, nil
+}
+
+// ExpireMillis converts a positive expire given in seconds, or in
+// milliseconds when millis is set, to milliseconds. It reports false for
+// values that are not positive or would overflow.
+func ExpireMillis(n int64, millis bool) (int64, bool) {
+	if n <= 0 {
+		return 0, false
+	}
+	if millis {
+		return n, true
+	}
+	if n > math.MaxInt64/1000 {
+		return 0, false
+	}
+	return n * 1000, true
+}
+
+// SetOptions holds SET's flags. TTLMillis is the relative expire, zero for
+// none.
+type SetOptions struct {
+	TTLMillis int64
+	NX        bool
+	XX        bool
+	KeepTTL   bool
+}
+
+// ParseSetOptions parses the options following SET's key and value, returning
+// the same errors Redis does: a non-numeric expire is ErrNotInteger, a
+// non-positive one is ErrInvalidExpire and any conflicting or unknown option
+// is ErrSyntax.
+func ParseSetOptions(args []string) (SetOptions, error) {
+	var opt SetOptions
+	hasExpire := false
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			if opt.XX {
+				return SetOptions{}, entity.ErrSyntax
+			}
+			opt.NX = true
+		case "XX":
+			if opt.NX {
+				return SetOptions{}, entity.ErrSyntax
+			}
+			opt.XX = true
+		case "KEEPTTL":
+			if hasExpire {
+				return SetOptions{}, entity.ErrSyntax
+			}
+			opt.KeepTTL = true
+		case "EX", "PX":
+			if hasExpire || opt.KeepTTL || i+1 >= len(args) {
+				return SetOptions{}, entity.ErrSyntax
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return SetOptions{}, entity.ErrNotInteger
+			}
+			ms, valid := ExpireMillis(n, strings.EqualFold(args[i], "PX"))
+			if !valid {
+				return SetOptions{}, entity.ErrInvalidExpire
+			}
+			opt.TTLMillis = ms
+			hasExpire = true
+			i++
+		default:
+			return SetOptions{}, entity.ErrSyntax
+		}
+	}
+	return opt, nil
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+func TestSetExOptions(t *testing.T) {
+	tests := []struct {
+		cmd    Type
+		expire string
+		want   int64
+	}{
+		{SETEX, "10", 10000},
+		{PSETEX, "100", 100},
+		{PSETEX, "1000", 1000},
+		{PSETEX, "1001", 1001},
+	}
+	for _, tt := range tests {
+		opt, err := SetExOptions(tt.cmd, tt.expire)
+		if err != nil || opt.TTLMillis != tt.want {
+			t.Errorf("%s %s = %d, %v; want %d ms", tt.cmd, tt.expire, opt.TTLMillis, err, tt.want)
+		}
+	}
+	if _, err := SetExOptions(PSETEX, "abc"); !errors.Is(err, entity.ErrNotInteger) {
+		t.Errorf("PSETEX abc = %v, want ErrNotInteger", err)
+	}
+	for _, expire := range []string{"0", "-100"} {
+		_, err := SetExOptions(PSETEX, expire)
+		if err == nil || err.Error() != "invalid expire time in 'psetex' command" {
+			t.Errorf("PSETEX %s = %v, want an invalid expire time error", expire, err)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "errors"
+
+var (
+	ErrEmptyCommand   = errors.New("empty command")
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrWrongArgs      = errors.New("wrong number of arguments")
+	ErrWrongType      = errors.New("Operation against a key holding the wrong kind of value")
+	ErrNotInteger     = errors.New("value is not an integer or out of range")
+	ErrSyntax         = errors.New("syntax error")
+	ErrNoSuchKey      = errors.New("no such key")
+	ErrNotHLL         = errors.New("Key is not a valid HyperLogLog string value.")
+	ErrOOM            = errors.New("command not allowed when used memory > 'maxmemory'.")
+	ErrValueTooLarge  = errors.New("string exceeds maximum allowed size (proto-max-bulk-len)")
+	ErrInvalidExpire  = errors.New("invalid expire time in 'set' command")
+	ErrMisconf        = errors.New("Errors writing to the AOF file")
+)
@@ -0,0 +1,10 @@
+package entity
+
+const (
+	EventSet     = "set"
+	EventDel     = "del"
+	EventExpire  = "expire"
+	EventPersist = "persist"
+	EventExpired = "expired"
+	EventPfadd   = "pfadd"
+)
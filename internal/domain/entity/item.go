@@ -1,8 +1,27 @@
 package entity
 
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	EncodingInt    = "int"
+	EncodingEmbstr = "embstr"
+	EncodingRaw    = "raw"
+
+	// EmbstrSizeLimit is the longest string Redis allocates together with
+	// its object header.
+	EmbstrSizeLimit = 44
+)
+
+// Item is a stored value. ExpiresAt and LastAccess are Unix times in
+// milliseconds.
 type Item struct {
-	Value     string
-	ExpiresAt *int64
+	Value      string
+	ExpiresAt  *int64
+	LastAccess int64
+	Compressed bool
 }
 
 func (i *Item) IsExpired(now int64) bool {
@@ -11,3 +30,37 @@ func (i *Item) IsExpired(now int64) bool {
 	}
 	return now > *i.ExpiresAt
 }
+
+// Touch records an access at now. It is atomic so readers holding only a
+// read lock can update it without serializing each other.
+func (i *Item) Touch(now int64) {
+	atomic.StoreInt64(&i.LastAccess, now)
+}
+
+// IdleTime returns the whole seconds since the last access.
+func (i *Item) IdleTime(now int64) int64 {
+	return max(now-atomic.LoadInt64(&i.LastAccess), 0) / 1000
+}
+
+func (i *Item) Encoding() string {
+	switch {
+	case i.Compressed:
+		return EncodingRaw
+	case IsIntEncodable(i.Value):
+		return EncodingInt
+	case len(i.Value) <= EmbstrSizeLimit:
+		return EncodingEmbstr
+	default:
+		return EncodingRaw
+	}
+}
+
+// IsIntEncodable reports whether value is the canonical decimal form of an
+// int64, which Redis stores as a machine integer instead of a string.
+func IsIntEncodable(value string) bool {
+	if len(value) == 0 || len(value) > 20 {
+		return false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && strconv.FormatInt(n, 10) == value
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsIntEncodable(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"0", true},
+		{"123", true},
+		{"-5", true},
+		{"9223372036854775807", true},
+		{"-9223372036854775808", true},
+		{"9223372036854775808", false},
+		{"123abc", false},
+		{"", false},
+		{"+1", false},
+		{"007", false},
+		{" 1", false},
+		{"1.5", false},
+	}
+	for _, tt := range tests {
+		if got := IsIntEncodable(tt.value); got != tt.want {
+			t.Errorf("IsIntEncodable(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestItemEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want string
+	}{
+		{"empty", Item{Value: ""}, EncodingEmbstr},
+		{"integer", Item{Value: "12345"}, EncodingInt},
+		{"43 bytes", Item{Value: strings.Repeat("x", 43)}, EncodingEmbstr},
+		{"44 bytes", Item{Value: strings.Repeat("x", 44)}, EncodingEmbstr},
+		{"45 bytes", Item{Value: strings.Repeat("x", 45)}, EncodingRaw},
+		{"compressed short", Item{Value: "x", Compressed: true}, EncodingRaw},
+	}
+	for _, tt := range tests {
+		if got := tt.item.Encoding(); got != tt.want {
+			t.Errorf("%s: Encoding = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
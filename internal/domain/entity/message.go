@@ -0,0 +1,6 @@
+package entity
+
+type Message struct {
+	Channel string
+	Payload string
+}
@@ -1,17 +1,67 @@
 package repository
 
-import "context"
+import (
+	"context"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+)
+
+type KeyspaceStats struct {
+	Keys    int
+	Expires int
+	AvgTTL  int64
+}
+
+// ObjectInfo describes how a key's value is held, as reported by OBJECT,
+// DEBUG OBJECT and MEMORY USAGE. StoredLength is the length of the value as
+// stored, which is smaller than the value itself when it is compressed.
+type ObjectInfo struct {
+	Encoding     string
+	StoredLength int
+	IdleTime     int64
+	MemoryUsage  int64
+}
 
 type KeyValueRepository interface {
-	Set(ctx context.Context, key, value string)
+	Set(ctx context.Context, key, value string) error
+	SetWithOptions(ctx context.Context, key, value string, opt command.SetOptions) (bool, error)
+	SetMany(ctx context.Context, items map[string]string) error
+	CompareAndSet(ctx context.Context, key, expected, value string) (bool, error)
 	Get(ctx context.Context, key string) (string, bool)
 	Del(ctx context.Context, key string) int
 	Expire(ctx context.Context, key string, durationInSeconds int) bool
 	TTL(ctx context.Context, key string) int64
+	PExpire(ctx context.Context, key string, ms int64) bool
+	PTTL(ctx context.Context, key string) int64
 	Persist(ctx context.Context, key string) bool
-	Keys(ctx context.Context, pattern string) []string
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	KeysInto(ctx context.Context, pattern string, buf []string) []string
+	Scan(ctx context.Context, cursor uint64, pattern string, count int) (uint64, []string)
+	CountKeys(ctx context.Context, pattern string, limit int) int
+	SnapshotKeysWithTTL(ctx context.Context) map[string]int64
 	Exists(ctx context.Context, key string) bool
+	Object(ctx context.Context, key string) (ObjectInfo, bool)
+	Peek(ctx context.Context, key string) (value string, expiresAt int64, exists bool)
 	Size(ctx context.Context) int
+	KeyspaceStats(ctx context.Context) KeyspaceStats
+	UsedMemory(ctx context.Context) int64
+	SetMaxMemory(bytes int64)
+	MaxMemory() int64
+	SetKeysLimit(limit int)
+	KeysLimit() int
+	FlushAll(ctx context.Context)
+	Restore(ctx context.Context, items map[string]string, expiresAt map[string]int64) error
+	Shrink(ctx context.Context)
+	BitPos(ctx context.Context, key string, bit int, start, end int, endGiven bool) int64
+	PFAdd(ctx context.Context, key string, elements ...string) (int, error)
+	PFCount(ctx context.Context, keys ...string) (int64, error)
+	PFMerge(ctx context.Context, dest string, sources ...string) error
 	StartCleanup(intervalInMs int64)
 	StopCleanup()
+	CleanupNow(ctx context.Context) int
+	SetCleanupInterval(intervalInMs int64)
+	CleanupInterval() int64
+	SetActiveExpire(enabled bool)
+	OnExpire(hook func(key string))
+	OnEvent(hook func(event, key string))
 }
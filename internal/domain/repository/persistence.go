@@ -2,8 +2,18 @@ package repository
 
 import "context"
 
+type PersistenceStats struct {
+	CurrentSize        int64
+	BufferLength       int64
+	RewriteInProgress  bool
+	LastRewriteSeconds int64
+}
+
 type PersistenceRepository interface {
 	Append(ctx context.Context, command string, args []string) error
 	Replay(ctx context.Context, store KeyValueRepository) error
+	SyncAOF() error
+	LastWriteError() error
+	Stats() PersistenceStats
 	Close() error
 }
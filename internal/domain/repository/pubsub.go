@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+// PubSubRepository delivers messages to subscriber channels. Publish never
+// blocks: a subscriber too slow to keep its channel drained is unsubscribed
+// from everything and its channel is closed. Subscribers must call
+// UnsubscribeAll once they are done with ch.
+type PubSubRepository interface {
+	Publish(ctx context.Context, channel, payload string) int
+	Subscribe(ctx context.Context, channel string, ch chan<- entity.Message)
+	Unsubscribe(ctx context.Context, channel string, ch chan<- entity.Message)
+	UnsubscribeAll(ctx context.Context, ch chan<- entity.Message)
+}
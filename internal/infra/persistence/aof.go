@@ -1,53 +1,159 @@
 package persistence
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/alexsandroveiga/redis-like-golang/internal/adapter/protocol"
 	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
 	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
 )
 
+// aofRetryInterval is how often a failed write is retried, as Redis does
+// from its once-a-second cron.
+const aofRetryInterval = time.Second
+
+// appendFile is the part of *os.File the AOF writes through.
+type appendFile interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
 type AOF struct {
-	filepath string
-	file     *os.File
-	mu       sync.Mutex
+	filepath     string
+	file         appendFile
+	mu           sync.Mutex
+	lastWriteErr error
+	size         int64
+	// pending holds bytes a failed write left unwritten; they are retried
+	// ahead of any new record.
+	pending       string
+	retrying      bool
+	retryInterval time.Duration
+	closed        chan struct{}
 }
 
 func NewAOF(filepath string) (repository.PersistenceRepository, error) {
-	file, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(filepath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
 	return &AOF{
-		filepath: filepath,
-		file:     file,
+		filepath:      filepath,
+		file:          file,
+		size:          info.Size(),
+		retryInterval: aofRetryInterval,
+		closed:        make(chan struct{}),
 	}, nil
 }
 
+// Append writes one record and fsyncs it before returning. Records are RESP
+// multibulk requests, as in Redis, so arguments keep their spaces, line breaks
+// and empty strings. On failure the record is kept and retried in the
+// background until a write and fsync succeed, which clears LastWriteError.
 func (a *AOF) Append(ctx context.Context, command string, args []string) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	line := command
-	if len(args) > 0 {
-		line = fmt.Sprintf("%s %s", line, strings.Join(args, " "))
+	a.pending += encodeRecord(command, args)
+	err := a.flush()
+	if err != nil && !a.retrying {
+		a.retrying = true
+		go a.retry()
 	}
-	line += "\n"
-	_, err := a.file.WriteString(line)
-	if err != nil {
-		return err
+	return err
+}
+
+// SyncAOF writes anything a failed Append left behind and forces an fsync of
+// the file, for callers that must not rely on the write policy.
+func (a *AOF) SyncAOF() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.flush()
+}
+
+// flush must be called with mu held. A short write keeps the unwritten tail
+// pending, so a retry completes the record rather than repeating it.
+func (a *AOF) flush() error {
+	if a.pending != "" {
+		n, err := io.WriteString(a.file, a.pending)
+		a.size += int64(n)
+		a.pending = a.pending[n:]
+		if err != nil {
+			a.lastWriteErr = err
+			return err
+		}
+	}
+	err := a.file.Sync()
+	a.lastWriteErr = err
+	return err
+}
+
+func (a *AOF) retry() {
+	ticker := time.NewTicker(a.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.closed:
+			return
+		case <-ticker.C:
+		}
+		a.mu.Lock()
+		a.retrying = a.flush() != nil
+		done := !a.retrying
+		a.mu.Unlock()
+		if done {
+			return
+		}
 	}
-	return a.file.Sync()
 }
 
+func (a *AOF) LastWriteError() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lastWriteErr
+}
+
+// Stats reports the writer state. Only bytes a failed write left behind are
+// ever buffered, and the file is never rewritten.
+func (a *AOF) Stats() repository.PersistenceStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return repository.PersistenceStats{
+		CurrentSize:        a.size,
+		BufferLength:       int64(len(a.pending)),
+		RewriteInProgress:  false,
+		LastRewriteSeconds: -1,
+	}
+}
+
+func encodeRecord(command string, args []string) string {
+	var sb strings.Builder
+	sb.WriteString("*" + strconv.Itoa(len(args)+1) + "\r\n")
+	for _, arg := range append([]string{command}, args...) {
+		sb.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	return sb.String()
+}
+
+// Replay applies the file to store. Files written before records were RESP
+// hold one space-separated command per line, which the reader still parses
+// as inline requests.
 func (a *AOF) Replay(ctx context.Context, store repository.KeyValueRepository) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -60,16 +166,30 @@ func (a *AOF) Replay(ctx context.Context, store repository.KeyValueRepository) e
 		return err
 	}
 	defer file.Close()
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
+	pending := make(map[string]string)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := store.SetMany(ctx, pending); err != nil {
+			return err
+		}
+		pending = make(map[string]string)
+		return nil
+	}
+	reader := protocol.NewReader(file, protocol.ReaderOption{MaxInlineLen: protocol.DefaultMaxBulkLen})
+	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+		parts, err := reader.ReadCommand()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// A crash mid-write leaves a truncated last record; drop it.
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading AOF file: %w", err)
 		}
-		parts := strings.Fields(line)
 		if len(parts) == 0 {
 			continue
 		}
@@ -81,8 +201,41 @@ func (a *AOF) Replay(ctx context.Context, store repository.KeyValueRepository) e
 				continue
 			}
 			key := args[0]
-			value := strings.Join(args[1:], " ")
-			store.Set(ctx, key, value)
+			if reader.Inline() {
+				// An inline record cannot tell a value with spaces from
+				// options, so everything after the key is the value, as
+				// older files wrote it.
+				pending[key] = strings.Join(args[1:], " ")
+				continue
+			}
+			if len(args) == 2 {
+				pending[key] = args[1]
+				continue
+			}
+			opt, err := command.ParseSetOptions(args[2:])
+			if err != nil {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			if _, err := store.SetWithOptions(ctx, key, args[1], opt); err != nil {
+				return err
+			}
+		case command.SETEX, command.PSETEX:
+			if len(args) != 3 {
+				continue
+			}
+			opt, err := command.SetExOptions(command.Type(cmd), args[1])
+			if err != nil {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			if _, err := store.SetWithOptions(ctx, args[0], args[2], opt); err != nil {
+				return err
+			}
 		case command.EXPIRE:
 			if len(args) < 2 {
 				continue
@@ -92,26 +245,79 @@ func (a *AOF) Replay(ctx context.Context, store repository.KeyValueRepository) e
 			if err != nil {
 				continue
 			}
+			if err := flush(); err != nil {
+				return err
+			}
 			store.Expire(ctx, key, seconds)
+		case command.PEXPIRE:
+			if len(args) < 2 {
+				continue
+			}
+			ms, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			store.PExpire(ctx, args[0], ms)
 		case command.DEL:
 			if len(args) < 1 {
 				continue
 			}
+			if err := flush(); err != nil {
+				return err
+			}
 			store.Del(ctx, args[0])
+		case command.PERSIST:
+			if len(args) < 1 {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			store.Persist(ctx, args[0])
+		case command.CAS:
+			if len(args) != 3 {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			store.CompareAndSet(ctx, args[0], args[1], args[2])
+		case command.PFADD:
+			if len(args) < 1 {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			store.PFAdd(ctx, args[0], args[1:]...)
+		case command.PFMERGE:
+			if len(args) < 1 {
+				continue
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			store.PFMerge(ctx, args[0], args[1:]...)
+		case command.FLUSHALL, command.FLUSHDB:
+			pending = make(map[string]string)
+			store.FlushAll(ctx)
 		default:
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading AOF file: %w", err)
-	}
-	return nil
+	return flush()
 }
 
 func (a *AOF) Close() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	if a.file != nil {
-		return a.file.Close()
+	select {
+	case <-a.closed:
+		return nil
+	default:
 	}
-	return nil
+	close(a.closed)
+	return a.file.Close()
 }
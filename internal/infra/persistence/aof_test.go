@@ -0,0 +1,214 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/storage"
+)
+
+func TestAOFAppendAndReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := NewAOF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	if err := aof.Append(ctx, "SET", []string{"k", "v"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := aof.SyncAOF(); err != nil {
+		t.Fatalf("SyncAOF: %v", err)
+	}
+	if err := aof.LastWriteError(); err != nil {
+		t.Fatalf("LastWriteError: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"; string(data) != want {
+		t.Fatalf("file = %q, want %q", data, want)
+	}
+	if size := aof.Stats().CurrentSize; size != int64(len(data)) {
+		t.Errorf("CurrentSize = %d, want %d", size, len(data))
+	}
+
+	store := storage.NewStore(storage.StoreOption{})
+	if err := aof.Replay(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get(ctx, "k"); value != "v" {
+		t.Errorf("replayed k = %q, want v", value)
+	}
+}
+
+func TestAOFRoundTripsArbitraryValues(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := NewAOF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	values := map[string]string{
+		"spaces":    "a  b   c ",
+		"crlf":      "line1\r\nline2\n",
+		"injection": "v\nFLUSHALL",
+		"empty":     "",
+	}
+	for key, value := range values {
+		if err := aof.Append(ctx, "SET", []string{key, value}); err != nil {
+			t.Fatalf("Append %s: %v", key, err)
+		}
+	}
+
+	store := storage.NewStore(storage.StoreOption{})
+	if err := aof.Replay(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range values {
+		if got, exists := store.Get(ctx, key); !exists || got != want {
+			t.Errorf("replayed %s = %q, %v; want %q", key, got, exists, want)
+		}
+	}
+}
+
+func TestAOFReplaysInlineRecords(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if err := os.WriteFile(path, []byte("SET a 1\nSET b 2\nDEL a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	aof, err := NewAOF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	if err := aof.Append(ctx, "SET", []string{"c", "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.NewStore(storage.StoreOption{})
+	if err := aof.Replay(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists(ctx, "a") {
+		t.Error("a survived its DEL")
+	}
+	for key, want := range map[string]string{"b": "2", "c": "3"} {
+		if got, _ := store.Get(ctx, key); got != want {
+			t.Errorf("replayed %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestAOFReplaysSetOptionsOnlyFromRESPRecords(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if err := os.WriteFile(path, []byte("SET legacy v EX 100\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	aof, err := NewAOF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aof.Close()
+	for _, args := range [][]string{
+		{"withttl", "v", "EX", "100"},
+		{"optionlike", "EX 100"},
+	} {
+		if err := aof.Append(ctx, "SET", args); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := storage.NewStore(storage.StoreOption{})
+	if err := aof.Replay(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key   string
+		value string
+		ttl   int64
+	}{
+		{"legacy", "v EX 100", -1},
+		{"withttl", "v", 100},
+		{"optionlike", "EX 100", -1},
+	}
+	for _, tt := range tests {
+		value, _ := store.Get(ctx, tt.key)
+		if ttl := store.TTL(ctx, tt.key); value != tt.value || ttl != tt.ttl {
+			t.Errorf("%s = %q with TTL %d, want %q with TTL %d", tt.key, value, ttl, tt.value, tt.ttl)
+		}
+	}
+}
+
+// flakyFile fails every write and fsync while broken is set.
+type flakyFile struct {
+	appendFile
+	broken atomic.Bool
+}
+
+var errDiskFull = errors.New("no space left on device")
+
+func (f *flakyFile) Write(p []byte) (int, error) {
+	if f.broken.Load() {
+		return 0, errDiskFull
+	}
+	return f.appendFile.Write(p)
+}
+
+func (f *flakyFile) Sync() error {
+	if f.broken.Load() {
+		return errDiskFull
+	}
+	return f.appendFile.Sync()
+}
+
+func TestAOFRecoversFromAFailedWrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	repo, err := NewAOF(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	aof := repo.(*AOF)
+	file := &flakyFile{appendFile: aof.file}
+	aof.file = file
+	aof.retryInterval = time.Millisecond
+
+	file.broken.Store(true)
+	if err := aof.Append(ctx, "SET", []string{"k", "v"}); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Append on a full disk = %v, want errDiskFull", err)
+	}
+	if err := aof.LastWriteError(); !errors.Is(err, errDiskFull) {
+		t.Fatalf("LastWriteError = %v, want errDiskFull", err)
+	}
+	if buffered := aof.Stats().BufferLength; buffered == 0 {
+		t.Error("BufferLength = 0 with a record waiting to be retried")
+	}
+	file.broken.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for aof.LastWriteError() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("LastWriteError never cleared after the disk recovered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	store := storage.NewStore(storage.StoreOption{})
+	if err := aof.Replay(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get(ctx, "k"); value != "v" {
+		t.Errorf("replayed k = %q, want the retried write", value)
+	}
+}
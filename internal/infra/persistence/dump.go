@@ -0,0 +1,216 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+)
+
+const (
+	dumpVersion   = 2
+	dumpScanCount = 100
+	dumpBatchSize = 1000
+)
+
+type dumpHeader struct {
+	Version int `json:"version"`
+}
+
+// dumpEntry is one key of a dump. Value is raw bytes, which JSON encodes as
+// base64, so binary values such as HyperLogLogs survive the round trip.
+// ExpiresAt is an absolute Unix time in seconds so a dump can be loaded later
+// without extending its TTLs.
+type dumpEntry struct {
+	Key       string `json:"key"`
+	Value     []byte `json:"value"`
+	ExpiresAt *int64 `json:"expires_at,omitempty"`
+}
+
+// dumpEntryV1 is the version 1 layout, which stored values as JSON strings
+// and so replaced invalid UTF-8 with U+FFFD.
+type dumpEntryV1 struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	ExpiresAt *int64 `json:"expires_at,omitempty"`
+}
+
+// Export writes the keyspace to w as newline-delimited JSON, preceded by a
+// version header. It walks the store with SCAN so the store lock is only held
+// per batch; keys that expire or are deleted mid-export are skipped.
+func Export(ctx context.Context, store repository.KeyValueRepository, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(dumpHeader{Version: dumpVersion}); err != nil {
+		return err
+	}
+	cursor := uint64(0)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var keys []string
+		cursor, keys = store.Scan(ctx, cursor, "*", dumpScanCount)
+		for _, key := range keys {
+			value, expiresAtMillis, exists := store.Peek(ctx, key)
+			if !exists {
+				continue
+			}
+			entry := dumpEntry{Key: key, Value: []byte(value)}
+			if expiresAtMillis > 0 {
+				expiresAt := (expiresAtMillis + 500) / 1000
+				entry.ExpiresAt = &expiresAt
+			}
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	return bw.Flush()
+}
+
+// Import loads a dump written by Export, applying keys in batches. Entries
+// whose absolute expiry has already passed are skipped. It returns the number
+// of keys loaded.
+func Import(ctx context.Context, store repository.KeyValueRepository, r io.Reader) (int, error) {
+	loaded := 0
+	values := make(map[string]string)
+	expires := make(map[string]int64)
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		if err := store.SetMany(ctx, values); err != nil {
+			return err
+		}
+		now := time.Now().UnixMilli()
+		for key, expiresAt := range expires {
+			store.PExpire(ctx, key, expiresAt*1000-now)
+		}
+		loaded += len(values)
+		values = make(map[string]string)
+		expires = make(map[string]int64)
+		return nil
+	}
+	err := readDump(ctx, r, func(entry dumpEntry) error {
+		if entry.ExpiresAt != nil {
+			expires[entry.Key] = *entry.ExpiresAt
+		} else {
+			delete(expires, entry.Key)
+		}
+		values[entry.Key] = string(entry.Value)
+		if len(values) >= dumpBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return loaded, err
+	}
+	return loaded, flush()
+}
+
+// readDump checks the header and calls visit for each entry that has not
+// expired, in file order.
+func readDump(ctx context.Context, r io.Reader, visit func(entry dumpEntry) error) error {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header dumpHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("invalid dump header: %w", err)
+	}
+	if header.Version != 1 && header.Version != dumpVersion {
+		return fmt.Errorf("unsupported dump version %d", header.Version)
+	}
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entry, err := decodeEntry(dec, header.Version)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid dump entry: %w", err)
+		}
+		if entry.ExpiresAt != nil && *entry.ExpiresAt <= time.Now().Unix() {
+			continue
+		}
+		if err := visit(entry); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeEntry(dec *json.Decoder, version int) (dumpEntry, error) {
+	if version == 1 {
+		var entry dumpEntryV1
+		err := dec.Decode(&entry)
+		return dumpEntry{Key: entry.Key, Value: []byte(entry.Value), ExpiresAt: entry.ExpiresAt}, err
+	}
+	var entry dumpEntry
+	err := dec.Decode(&entry)
+	return entry, err
+}
+
+// Reload round-trips the keyspace through a dump, exercising the same
+// encoding a restore from disk would use. The dump is decoded in full before
+// the keyspace is replaced in one step, so a failed reload leaves the old data
+// in place.
+func Reload(ctx context.Context, store repository.KeyValueRepository) error {
+	var buf bytes.Buffer
+	if err := Export(ctx, store, &buf); err != nil {
+		return err
+	}
+	return restore(ctx, store, &buf)
+}
+
+func restore(ctx context.Context, store repository.KeyValueRepository, r io.Reader) error {
+	values := make(map[string]string)
+	expires := make(map[string]int64)
+	err := readDump(ctx, r, func(entry dumpEntry) error {
+		if entry.ExpiresAt != nil {
+			expires[entry.Key] = *entry.ExpiresAt * 1000
+		} else {
+			delete(expires, entry.Key)
+		}
+		values[entry.Key] = string(entry.Value)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return store.Restore(ctx, values, expires)
+}
+
+// SaveFile exports the keyspace to path, writing to a temporary file first so
+// an interrupted save never leaves a truncated dump behind.
+func SaveFile(ctx context.Context, store repository.KeyValueRepository, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "temp-*.ndjson")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := Export(ctx, store, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,174 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/clock"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/storage"
+)
+
+func TestDumpRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := storage.NewStore(storage.StoreOption{})
+	if err := src.Set(ctx, "plain", "value"); err != nil {
+		t.Fatal(err)
+	}
+	binary := string([]byte{0x00, 0xff, 0xfe, 0x80, '\n'})
+	if err := src.Set(ctx, "binary", binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Set(ctx, "ttl", "v"); err != nil {
+		t.Fatal(err)
+	}
+	src.Expire(ctx, "ttl", 100)
+	if _, err := src.PFAdd(ctx, "hll", "a", "b", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(ctx, src, &buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := storage.NewStore(storage.StoreOption{})
+	loaded, err := Import(ctx, dst, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 4 {
+		t.Fatalf("loaded %d keys, want 4", loaded)
+	}
+	for _, key := range []string{"plain", "binary", "ttl", "hll"} {
+		want, _ := src.Get(ctx, key)
+		got, exists := dst.Get(ctx, key)
+		if !exists || got != want {
+			t.Errorf("%s = %q, %v; want %q", key, got, exists, want)
+		}
+	}
+	if ttl := dst.TTL(ctx, "ttl"); ttl < 99 || ttl > 100 {
+		t.Errorf("ttl = %d, want about 100", ttl)
+	}
+	count, err := dst.PFCount(ctx, "hll")
+	if err != nil {
+		t.Fatalf("PFCOUNT after import: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("PFCOUNT = %d, want 3", count)
+	}
+}
+
+func TestExportDoesNotTouchKeys(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock(time.Now())
+	store := storage.NewStore(storage.StoreOption{Clock: clk})
+	if err := store.Set(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(time.Minute)
+	if err := Export(ctx, store, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := store.Object(ctx, "k"); info.IdleTime != 60 {
+		t.Errorf("IDLETIME after Export = %d, want 60", info.IdleTime)
+	}
+}
+
+func TestImportVersion1(t *testing.T) {
+	ctx := context.Background()
+	dump := "{\"version\":1}\n{\"key\":\"k\",\"value\":\"v\"}\n"
+	store := storage.NewStore(storage.StoreOption{})
+	if _, err := Import(ctx, store, strings.NewReader(dump)); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.Get(ctx, "k"); value != "v" {
+		t.Errorf("k = %q, want v", value)
+	}
+}
+
+func TestImportKnownDump(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	// "dg==" is base64 for "v" and "AP8=" for the bytes 0x00 0xff.
+	dump := fmt.Sprintf(`{"version":2}
+{"key":"plain","value":"dg=="}
+{"key":"binary","value":"AP8="}
+{"key":"ttl","value":"dg==","expires_at":%d}
+{"key":"expired","value":"dg==","expires_at":%d}
+`, now+500, now-1)
+	store := storage.NewStore(storage.StoreOption{})
+	loaded, err := Import(ctx, store, strings.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 3 {
+		t.Errorf("loaded %d keys, want 3", loaded)
+	}
+	for key, want := range map[string]string{"plain": "v", "binary": "\x00\xff", "ttl": "v"} {
+		if got, _ := store.Get(ctx, key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if store.Exists(ctx, "expired") {
+		t.Error("an entry that had already expired was loaded")
+	}
+	if ttl := store.TTL(ctx, "plain"); ttl != -1 {
+		t.Errorf("TTL plain = %d, want no expiry", ttl)
+	}
+	if ttl := store.TTL(ctx, "ttl"); ttl < 499 || ttl > 500 {
+		t.Errorf("TTL ttl = %d, want about 500", ttl)
+	}
+}
+
+func TestImportRejectsBadInput(t *testing.T) {
+	tests := map[string]string{
+		"empty":           "",
+		"not json":        "hello\n",
+		"unknown version": "{\"version\":3}\n",
+		"bad entry":       "{\"version\":2}\n{\"key\":\"k\",\"value\":\"not base64!\"}\n",
+	}
+	for name, dump := range tests {
+		store := storage.NewStore(storage.StoreOption{})
+		if _, err := Import(context.Background(), store, strings.NewReader(dump)); err == nil {
+			t.Errorf("%s: Import accepted an invalid dump", name)
+		}
+	}
+}
+
+func TestFailedReloadKeepsTheKeyspace(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewStore(storage.StoreOption{})
+	if err := store.Set(ctx, "old", "v"); err != nil {
+		t.Fatal(err)
+	}
+	dump := "{\"version\":2}\n{\"key\":\"new\",\"value\":\"dg==\"}\n{\"key\":\"k\",\"value\":\"not base64!\"}\n"
+	if err := restore(ctx, store, strings.NewReader(dump)); err == nil {
+		t.Fatal("restore accepted a dump that fails partway")
+	}
+	if value, _ := store.Get(ctx, "old"); value != "v" {
+		t.Errorf("old = %q after a failed reload, want v", value)
+	}
+	if store.Exists(ctx, "new") {
+		t.Error("a failed reload applied part of the dump")
+	}
+}
+
+func TestReloadIgnoresMaxMemory(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewStore(storage.StoreOption{})
+	for i := range 10 {
+		if err := store.Set(ctx, fmt.Sprintf("k%d", i), "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.SetMaxMemory(1)
+	if err := Reload(ctx, store); err != nil {
+		t.Fatalf("Reload after lowering maxmemory: %v", err)
+	}
+	if size := store.Size(ctx); size != 10 {
+		t.Errorf("Size after Reload = %d, want 10", size)
+	}
+}
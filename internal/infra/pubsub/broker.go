@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+)
+
+// Broker fans messages out to subscriber channels. A subscriber whose buffer
+// is full is treated like a Redis client over its pubsub output buffer limit
+// and disconnected, rather than having messages silently skipped.
+type Broker struct {
+	channels map[string]map[chan<- entity.Message]struct{}
+	// dropped holds closed subscriber channels until UnsubscribeAll, so a
+	// late Subscribe cannot register a closed channel.
+	dropped map[chan<- entity.Message]struct{}
+	mu      sync.RWMutex
+}
+
+func NewBroker() repository.PubSubRepository {
+	return &Broker{
+		channels: make(map[string]map[chan<- entity.Message]struct{}),
+		dropped:  make(map[chan<- entity.Message]struct{}),
+	}
+}
+
+func (b *Broker) Publish(ctx context.Context, channel, payload string) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	msg := entity.Message{Channel: channel, Payload: payload}
+	delivered := 0
+	var full []chan<- entity.Message
+	b.mu.RLock()
+	for ch := range b.channels[channel] {
+		select {
+		case ch <- msg:
+			delivered++
+		default:
+			full = append(full, ch)
+		}
+	}
+	b.mu.RUnlock()
+	if len(full) > 0 {
+		b.drop(full)
+	}
+	return delivered
+}
+
+func (b *Broker) drop(subscribers []chan<- entity.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range subscribers {
+		if _, exists := b.dropped[ch]; exists {
+			continue
+		}
+		b.remove(ch)
+		b.dropped[ch] = struct{}{}
+		close(ch)
+	}
+}
+
+func (b *Broker) Subscribe(ctx context.Context, channel string, ch chan<- entity.Message) {
+	if ctx.Err() != nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exists := b.dropped[ch]; exists {
+		return
+	}
+	subscribers, exists := b.channels[channel]
+	if !exists {
+		subscribers = make(map[chan<- entity.Message]struct{})
+		b.channels[channel] = subscribers
+	}
+	subscribers[ch] = struct{}{}
+}
+
+func (b *Broker) Unsubscribe(ctx context.Context, channel string, ch chan<- entity.Message) {
+	if ctx.Err() != nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subscribers, exists := b.channels[channel]
+	if !exists {
+		return
+	}
+	delete(subscribers, ch)
+	if len(subscribers) == 0 {
+		delete(b.channels, channel)
+	}
+}
+
+func (b *Broker) UnsubscribeAll(ctx context.Context, ch chan<- entity.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(ch)
+	delete(b.dropped, ch)
+}
+
+func (b *Broker) remove(ch chan<- entity.Message) {
+	for channel, subscribers := range b.channels {
+		delete(subscribers, ch)
+		if len(subscribers) == 0 {
+			delete(b.channels, channel)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+func TestPublishDropsFullSubscriber(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker()
+	slow := make(chan entity.Message, 1)
+	fast := make(chan entity.Message, 4)
+	b.Subscribe(ctx, "news", slow)
+	b.Subscribe(ctx, "other", slow)
+	b.Subscribe(ctx, "news", fast)
+
+	if n := b.Publish(ctx, "news", "1"); n != 2 {
+		t.Fatalf("first publish delivered to %d, want 2", n)
+	}
+	if n := b.Publish(ctx, "news", "2"); n != 1 {
+		t.Fatalf("second publish delivered to %d, want 1", n)
+	}
+	if msg := <-slow; msg.Payload != "1" {
+		t.Errorf("slow got %q, want 1", msg.Payload)
+	}
+	if _, open := <-slow; open {
+		t.Error("slow subscriber's channel should be closed after overflow")
+	}
+	if n := b.Publish(ctx, "other", "3"); n != 0 {
+		t.Errorf("dropped subscriber still receives on other channels")
+	}
+	b.Subscribe(ctx, "news", slow)
+	if n := b.Publish(ctx, "news", "4"); n != 1 {
+		t.Errorf("publish after resubscribing a dropped channel delivered to %d, want 1", n)
+	}
+	b.UnsubscribeAll(ctx, slow)
+	b.UnsubscribeAll(ctx, fast)
+	if n := b.Publish(ctx, "news", "5"); n != 0 {
+		t.Errorf("publish after UnsubscribeAll delivered to %d, want 0", n)
+	}
+}
@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+)
+
+const (
+	classKeyspace = 'K'
+	classKeyevent = 'E'
+	classGeneric  = 'g'
+	classString   = '$'
+	classExpired  = 'x'
+	classAll      = 'A'
+)
+
+var eventClasses = map[string]rune{
+	entity.EventSet:     classString,
+	entity.EventDel:     classGeneric,
+	entity.EventExpire:  classGeneric,
+	entity.EventPersist: classGeneric,
+	entity.EventExpired: classExpired,
+	entity.EventPfadd:   classString,
+}
+
+type KeyspaceNotifierOption struct {
+	NotifyKeyspaceEvents string
+}
+
+type KeyspaceNotifier struct {
+	publisher repository.PubSubRepository
+	flags     map[rune]bool
+}
+
+func NewKeyspaceNotifier(publisher repository.PubSubRepository, opt KeyspaceNotifierOption) (*KeyspaceNotifier, error) {
+	flags, err := parseKeyspaceEvents(opt.NotifyKeyspaceEvents)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyspaceNotifier{
+		publisher: publisher,
+		flags:     flags,
+	}, nil
+}
+
+func (n *KeyspaceNotifier) Attach(store repository.KeyValueRepository) {
+	store.OnEvent(n.Notify)
+	store.OnExpire(func(key string) {
+		n.Notify(entity.EventExpired, key)
+	})
+}
+
+func (n *KeyspaceNotifier) Notify(event, key string) {
+	class, exists := eventClasses[event]
+	if !exists || !n.flags[class] {
+		return
+	}
+	ctx := context.Background()
+	if n.flags[classKeyspace] {
+		n.publisher.Publish(ctx, fmt.Sprintf("__keyspace@0__:%s", key), event)
+	}
+	if n.flags[classKeyevent] {
+		n.publisher.Publish(ctx, fmt.Sprintf("__keyevent@0__:%s", event), key)
+	}
+}
+
+func parseKeyspaceEvents(config string) (map[rune]bool, error) {
+	flags := make(map[rune]bool)
+	for _, c := range config {
+		switch c {
+		case classAll:
+			for _, class := range "g$lshzxetd" {
+				flags[class] = true
+			}
+		case classKeyspace, classKeyevent, classGeneric, classString, classExpired,
+			'l', 's', 'h', 'z', 'e', 't', 'd', 'm', 'n':
+			flags[c] = true
+		default:
+			return nil, fmt.Errorf("invalid notify-keyspace-events flag: %c", c)
+		}
+	}
+	return flags, nil
+}
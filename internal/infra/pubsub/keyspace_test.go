@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/clock"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/storage"
+)
+
+func TestExpiredKeyeventNotification(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewMock(time.Unix(1_700_000_000, 0))
+	store := storage.NewStore(storage.StoreOption{Clock: clk})
+	broker := NewBroker()
+	notifier, err := NewKeyspaceNotifier(broker, KeyspaceNotifierOption{NotifyKeyspaceEvents: "Ex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.Attach(store)
+	messages := make(chan entity.Message, 8)
+	broker.Subscribe(ctx, "__keyevent@0__:expired", messages)
+
+	store.Set(ctx, "session", "v")
+	store.Expire(ctx, "session", 1)
+	clk.Advance(2 * time.Second)
+	store.CleanupNow(ctx)
+
+	select {
+	case msg := <-messages:
+		if msg.Payload != "session" {
+			t.Errorf("expired notification for %q, want session", msg.Payload)
+		}
+	default:
+		t.Fatal("no expired notification")
+	}
+	select {
+	case msg := <-messages:
+		t.Errorf("unexpected notification %+v; only expired events were enabled", msg)
+	default:
+	}
+}
+
+func TestParseKeyspaceEvents(t *testing.T) {
+	if _, err := NewKeyspaceNotifier(NewBroker(), KeyspaceNotifierOption{NotifyKeyspaceEvents: "Kq"}); err == nil {
+		t.Error("unknown class q accepted")
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"math/bits"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+// BitPos returns the position of the first bit equal to bit within the byte
+// range [start, end]. Like Redis, a clear bit past the end of the string is
+// reported when searching for 0 without an explicit end.
+func (s *Store) BitPos(ctx context.Context, key string, bit int, start, end int, endGiven bool) int64 {
+	if ctx.Err() != nil {
+		return -1
+	}
+	var value string
+	if !s.readLive(key, func(item *entity.Item) { value = itemValue(item) }) {
+		if bit == 1 {
+			return -1
+		}
+		return 0
+	}
+	length := len(value)
+	if !endGiven {
+		end = length - 1
+	}
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start, end = max(start, 0), min(max(end, 0), length-1)
+	if length == 0 || start > end {
+		return -1
+	}
+	for i := start; i <= end; i++ {
+		b := value[i]
+		if bit == 0 {
+			b = ^b
+		}
+		if b != 0 {
+			return int64(i*8 + bits.LeadingZeros8(b))
+		}
+	}
+	if bit == 0 && !endGiven {
+		return int64((end + 1) * 8)
+	}
+	return -1
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBitPos(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	values := map[string]string{
+		"ones":  "\xff\xff\xff",
+		"zeros": "\x00\x00\x00",
+		"mixed": "\xff\xf0\x00",
+		"empty": "",
+	}
+	for key, value := range values {
+		if err := s.Set(ctx, key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name       string
+		key        string
+		bit        int
+		start, end int
+		endGiven   bool
+		want       int64
+	}{
+		{"first set bit", "mixed", 1, 0, 0, false, 0},
+		{"first clear bit", "mixed", 0, 0, 0, false, 12},
+		{"clear bit past the end of all ones", "ones", 0, 0, 0, false, 24},
+		{"clear bit in all ones with explicit end", "ones", 0, 0, 2, true, -1},
+		{"set bit in all zeros", "zeros", 1, 0, 0, false, -1},
+		{"clear bit in all zeros", "zeros", 0, 0, 0, false, 0},
+		{"start skips bytes", "mixed", 1, 1, 0, false, 8},
+		{"negative start", "mixed", 0, -1, 0, false, 16},
+		{"negative range", "mixed", 1, -2, -2, true, 8},
+		{"start past end", "mixed", 1, 2, 1, true, -1},
+		{"end clamped to length", "mixed", 0, 1, 100, true, 12},
+		{"missing key looking for 1", "missing", 1, 0, 0, false, -1},
+		{"missing key looking for 0", "missing", 0, 0, 0, false, 0},
+		{"empty string", "empty", 1, 0, 0, false, -1},
+	}
+	for _, tt := range tests {
+		if got := s.BitPos(ctx, tt.key, tt.bit, tt.start, tt.end, tt.endGiven); got != tt.want {
+			t.Errorf("%s: BitPos = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
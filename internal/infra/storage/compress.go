@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+// compressValue deflates value, reporting false when that would not save
+// space so incompressible values are kept as they are.
+func compressValue(value string) (string, bool) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return value, false
+	}
+	if _, err := io.WriteString(w, value); err != nil {
+		return value, false
+	}
+	if err := w.Close(); err != nil || buf.Len() >= len(value) {
+		return value, false
+	}
+	return buf.String(), true
+}
+
+func itemValue(item *entity.Item) string {
+	if !item.Compressed {
+		return item.Value
+	}
+	value, err := io.ReadAll(flate.NewReader(strings.NewReader(item.Value)))
+	if err != nil {
+		return item.Value
+	}
+	return string(value)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+func TestCompressedValueRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	value := strings.Repeat("compressible ", 1000)
+	plain, _ := newTestStore(t, StoreOption{})
+	compressed, _ := newTestStore(t, StoreOption{CompressThreshold: 1024})
+	for _, s := range []*Store{plain, compressed} {
+		if err := s.Set(ctx, "big", value); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := s.Get(ctx, "big"); got != value {
+			t.Fatal("value did not round-trip")
+		}
+	}
+	want, _ := plain.Object(ctx, "big")
+	got, _ := compressed.Object(ctx, "big")
+	if got.MemoryUsage >= want.MemoryUsage/10 {
+		t.Errorf("compressed MemoryUsage = %d, uncompressed %d", got.MemoryUsage, want.MemoryUsage)
+	}
+	if got.StoredLength >= len(value) {
+		t.Errorf("StoredLength = %d, want less than %d", got.StoredLength, len(value))
+	}
+	if got.Encoding != entity.EncodingRaw {
+		t.Errorf("Encoding = %q, want raw", got.Encoding)
+	}
+	if used := compressed.UsedMemory(ctx); used != got.MemoryUsage {
+		t.Errorf("UsedMemory = %d, want %d", used, got.MemoryUsage)
+	}
+}
+
+func TestIncompressibleValueStoredPlain(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{CompressThreshold: 4})
+	if err := s.Set(ctx, "n", "12345"); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := s.Object(ctx, "n")
+	if info.Encoding != entity.EncodingInt {
+		t.Errorf("Encoding = %q, want int", info.Encoding)
+	}
+}
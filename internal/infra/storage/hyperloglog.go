@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+const (
+	hllP         = 14
+	hllQ         = 64 - hllP
+	hllRegisters = 1 << hllP
+	hllBits      = 6
+	hllRegMax    = (1 << hllBits) - 1
+	hllHdrSize   = 16
+	hllDenseSize = hllHdrSize + (hllRegisters*hllBits+7)/8
+	hllDense     = 0
+	hllAlphaInf  = 0.721347520444481703680
+	hllSeed      = 0xadc83b19
+)
+
+func (s *Store) PFAdd(ctx context.Context, key string, elements ...string) (int, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	hook, changed, err := s.pfadd(key, elements)
+	if !changed {
+		return 0, err
+	}
+	emit(hook, entity.EventPfadd, key)
+	return 1, nil
+}
+
+func (s *Store) pfadd(key string, elements []string) (EventHook, bool, error) {
+	s.mu.Lock()
+	defer s.unlock()
+	if err := s.checkMemory(); err != nil {
+		return nil, false, err
+	}
+	item, exists := s.data[key]
+	if exists && item.IsExpired(s.now()) {
+		exists = false
+	}
+	var registers []byte
+	changed := !exists
+	if exists {
+		var err error
+		registers, err = hllDecode(itemValue(item))
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		registers = make([]byte, hllDenseSize-hllHdrSize)
+	}
+	for _, element := range elements {
+		index, count := hllPatLen([]byte(element))
+		if count > hllGetRegister(registers, index) {
+			hllSetRegister(registers, index, count)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false, nil
+	}
+	value := hllEncode(registers)
+	if exists {
+		s.update(item, value)
+	} else {
+		s.put(key, &entity.Item{Value: value, ExpiresAt: nil})
+	}
+	return s.onEvent, true, nil
+}
+
+func (s *Store) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	merged, err := s.hllMerge(keys)
+	if err != nil {
+		return 0, err
+	}
+	return hllCount(merged), nil
+}
+
+func (s *Store) PFMerge(ctx context.Context, dest string, sources ...string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	hook, err := s.pfmerge(dest, sources)
+	if err != nil {
+		return err
+	}
+	emit(hook, entity.EventPfadd, dest)
+	return nil
+}
+
+func (s *Store) pfmerge(dest string, sources []string) (EventHook, error) {
+	s.mu.Lock()
+	defer s.unlock()
+	if err := s.checkMemory(); err != nil {
+		return nil, err
+	}
+	merged, err := s.hllMerge(append([]string{dest}, sources...))
+	if err != nil {
+		return nil, err
+	}
+	value := hllEncode(merged)
+	item, exists := s.data[dest]
+	if exists && !item.IsExpired(s.now()) {
+		s.update(item, value)
+	} else {
+		s.put(dest, &entity.Item{Value: value, ExpiresAt: nil})
+	}
+	return s.onEvent, nil
+}
+
+func (s *Store) hllMerge(keys []string) ([]byte, error) {
+	now := s.now()
+	merged := make([]byte, hllDenseSize-hllHdrSize)
+	for _, key := range keys {
+		item, exists := s.data[key]
+		if !exists || item.IsExpired(now) {
+			continue
+		}
+		registers, err := hllDecode(itemValue(item))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < hllRegisters; i++ {
+			if value := hllGetRegister(registers, i); value > hllGetRegister(merged, i) {
+				hllSetRegister(merged, i, value)
+			}
+		}
+	}
+	return merged, nil
+}
+
+func hllDecode(value string) ([]byte, error) {
+	if len(value) != hllDenseSize || value[:4] != "HYLL" || value[4] != hllDense {
+		return nil, entity.ErrNotHLL
+	}
+	registers := make([]byte, hllDenseSize-hllHdrSize)
+	copy(registers, value[hllHdrSize:])
+	return registers, nil
+}
+
+func hllEncode(registers []byte) string {
+	buf := make([]byte, hllDenseSize)
+	copy(buf, "HYLL")
+	buf[4] = hllDense
+	buf[15] = 1 << 7
+	copy(buf[hllHdrSize:], registers)
+	return string(buf)
+}
+
+func hllGetRegister(registers []byte, index int) uint8 {
+	bit := index * hllBits
+	b := bit / 8
+	fb := uint(bit & 7)
+	value := uint(registers[b]) >> fb
+	if b+1 < len(registers) {
+		value |= uint(registers[b+1]) << (8 - fb)
+	}
+	return uint8(value & hllRegMax)
+}
+
+func hllSetRegister(registers []byte, index int, value uint8) {
+	bit := index * hllBits
+	b := bit / 8
+	fb := uint(bit & 7)
+	v := uint(value)
+	registers[b] &^= byte(hllRegMax << fb)
+	registers[b] |= byte(v << fb)
+	if b+1 < len(registers) {
+		registers[b+1] &^= byte(hllRegMax >> (8 - fb))
+		registers[b+1] |= byte(v >> (8 - fb))
+	}
+}
+
+func hllPatLen(element []byte) (int, uint8) {
+	hash := murmurHash64A(element, hllSeed)
+	index := int(hash & (hllRegisters - 1))
+	hash >>= hllP
+	hash |= 1 << hllQ
+	count := uint8(1)
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+	return index, count
+}
+
+func hllCount(registers []byte) int64 {
+	var histogram [hllQ + 2]int
+	for i := 0; i < hllRegisters; i++ {
+		histogram[hllGetRegister(registers, i)]++
+	}
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return int64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+func murmurHash64A(data []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ (uint64(len(data)) * m)
+	end := len(data) - len(data)&7
+	for i := 0; i < end; i += 8 {
+		k := binary.LittleEndian.Uint64(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	tail := data[end:]
+	for i := len(tail) - 1; i >= 0; i-- {
+		h ^= uint64(tail[i]) << (8 * uint(i))
+	}
+	if len(tail) > 0 {
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+)
+
+// hllTolerance is three standard errors of a 2^14 register HyperLogLog.
+var hllTolerance = 3 * 1.04 / math.Sqrt(hllRegisters)
+
+func pfaddRange(t *testing.T, s *Store, key string, from, to int) {
+	t.Helper()
+	ctx := context.Background()
+	batch := make([]string, 0, 1000)
+	for i := from; i < to; i++ {
+		batch = append(batch, "element:"+strconv.Itoa(i))
+		if len(batch) == cap(batch) || i == to-1 {
+			if _, err := s.PFAdd(ctx, key, batch...); err != nil {
+				t.Fatal(err)
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+func checkEstimate(t *testing.T, got int64, want int) {
+	t.Helper()
+	if diff := math.Abs(float64(got)-float64(want)) / float64(want); diff > hllTolerance {
+		t.Errorf("estimate %d for %d elements is off by %.2f%%, want within %.2f%%", got, want, diff*100, hllTolerance*100)
+	}
+}
+
+func TestPFCountErrorBound(t *testing.T) {
+	ctx := context.Background()
+	for _, n := range []int{100, 1000, 10000, 100000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			s, _ := newTestStore(t, StoreOption{})
+			pfaddRange(t, s, "hll", 0, n)
+			pfaddRange(t, s, "hll", 0, n/2)
+			count, err := s.PFCount(ctx, "hll")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEstimate(t, count, n)
+		})
+	}
+}
+
+func TestPFMerge(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	pfaddRange(t, s, "a", 0, 30000)
+	pfaddRange(t, s, "b", 20000, 50000)
+	if err := s.PFMerge(ctx, "union", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	count, err := s.PFCount(ctx, "union")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEstimate(t, count, 50000)
+	if multi, _ := s.PFCount(ctx, "a", "b"); multi != count {
+		t.Errorf("PFCOUNT a b = %d, want the merged count %d", multi, count)
+	}
+}
+
+func TestPFAddWrongType(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	if err := s.Set(ctx, "k", "not an hll"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PFAdd(ctx, "k", "x"); !errors.Is(err, entity.ErrNotHLL) {
+		t.Errorf("PFADD on a string = %v, want ErrNotHLL", err)
+	}
+	if _, err := s.PFCount(ctx, "k"); !errors.Is(err, entity.ErrNotHLL) {
+		t.Errorf("PFCOUNT on a string = %v, want ErrNotHLL", err)
+	}
+}
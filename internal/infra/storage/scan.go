@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"hash/maphash"
+	"math/bits"
+)
+
+const (
+	scanMinBuckets   = 16
+	scanLoadFactor   = 8
+	scanMaxVisits    = 10
+	defaultScanCount = 10
+	// scanEntrySize estimates what a key costs the index: its map slot and
+	// string header. The key bytes are shared with the data map.
+	scanEntrySize = 24
+)
+
+// scanIndex partitions keys into a power-of-two number of buckets by hash so
+// a SCAN cursor can address a bucket. The table doubles once buckets average
+// more than scanLoadFactor keys, which keeps the work of one SCAN call close
+// to its COUNT.
+//
+// Cursors visit buckets in reverse-binary order, as Redis does. When the table
+// doubles, bucket b splits into b and b+size, and both sort after any cursor
+// that had already passed b; so every key present for a whole iteration is
+// returned at least once, and exactly once if the table was not resized.
+// Shrinking may return some keys twice. Keys added or removed mid-iteration
+// may or may not be returned.
+type scanIndex struct {
+	seed    maphash.Seed
+	buckets []map[string]struct{}
+	keys    int
+}
+
+func newScanIndex() *scanIndex {
+	return &scanIndex{
+		seed:    maphash.MakeSeed(),
+		buckets: make([]map[string]struct{}, scanMinBuckets),
+	}
+}
+
+func (idx *scanIndex) add(key string) {
+	idx.keys++
+	if idx.keys > len(idx.buckets)*scanLoadFactor {
+		idx.resize(len(idx.buckets) * 2)
+	}
+	b := idx.bucket(key)
+	if idx.buckets[b] == nil {
+		idx.buckets[b] = make(map[string]struct{})
+	}
+	idx.buckets[b][key] = struct{}{}
+}
+
+func (idx *scanIndex) remove(key string) {
+	b := idx.bucket(key)
+	delete(idx.buckets[b], key)
+	if len(idx.buckets[b]) == 0 {
+		idx.buckets[b] = nil
+	}
+	idx.keys--
+}
+
+// fit resizes the table to the smallest size that holds the current keys
+// within the load factor, releasing buckets left behind by deletions.
+func (idx *scanIndex) fit() {
+	size := scanMinBuckets
+	for size*scanLoadFactor < idx.keys {
+		size *= 2
+	}
+	if size != len(idx.buckets) {
+		idx.resize(size)
+	}
+}
+
+func (idx *scanIndex) resize(size int) {
+	old := idx.buckets
+	idx.buckets = make([]map[string]struct{}, size)
+	for _, keys := range old {
+		for key := range keys {
+			b := idx.bucket(key)
+			if idx.buckets[b] == nil {
+				idx.buckets[b] = make(map[string]struct{})
+			}
+			idx.buckets[b][key] = struct{}{}
+		}
+	}
+}
+
+func (idx *scanIndex) mask() uint64 {
+	return uint64(len(idx.buckets) - 1)
+}
+
+func (idx *scanIndex) bucket(key string) uint64 {
+	return maphash.String(idx.seed, key) & idx.mask()
+}
+
+// nextCursor increments the bits of cursor covered by mask in reverse order,
+// wrapping to 0 once every bucket has been visited.
+func nextCursor(cursor, mask uint64) uint64 {
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// Scan returns the keys of the buckets starting at cursor until it has
+// examined count keys, which may overshoot by up to one bucket. To bound the
+// work over a sparse table it also stops after visiting 10*count buckets,
+// possibly returning no keys with a non-zero cursor.
+func (s *Store) Scan(ctx context.Context, cursor uint64, pattern string, count int) (uint64, []string) {
+	if ctx.Err() != nil {
+		return 0, []string{}
+	}
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cursor, keys, _ := s.scan(cursor, pattern, count)
+	return cursor, keys
+}
+
+// scan is Scan under the read lock. It also returns how many keys it
+// examined, matching or not.
+func (s *Store) scan(cursor uint64, pattern string, count int) (uint64, []string, int) {
+	now := s.now()
+	keys := []string{}
+	mask := s.index.mask()
+	examined := 0
+	for visits := 0; examined < count && visits < count*scanMaxVisits; visits++ {
+		for key := range s.index.buckets[cursor&mask] {
+			examined++
+			if s.data[key].IsExpired(now) || !matchPattern(key, pattern) {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		cursor = nextCursor(cursor, mask)
+		if cursor == 0 {
+			break
+		}
+	}
+	return cursor, keys, examined
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func fillStore(t testing.TB, s *Store, n int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		if err := s.Set(ctx, "key:"+strconv.Itoa(i), "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanWorkIsBoundedByCount(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	fillStore(t, s, 10000)
+	cursor, keys := s.Scan(ctx, 0, "*", 10)
+	if cursor == 0 {
+		t.Fatal("one SCAN with COUNT 10 finished a 10000-key iteration")
+	}
+	if len(keys) < 10 || len(keys) > 10+4*scanLoadFactor {
+		t.Errorf("SCAN COUNT 10 returned %d keys", len(keys))
+	}
+}
+
+func TestScanWorkIsBoundedBySparseMatches(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	fillStore(t, s, 10000)
+	for i := 0; i < 3; i++ {
+		if err := s.Set(ctx, "needle:"+strconv.Itoa(i), "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	largest := 0
+	for _, bucket := range s.index.buckets {
+		largest = max(largest, len(bucket))
+	}
+	cursor, _, examined := s.scan(0, "needle:*", 10)
+	if cursor == 0 {
+		t.Fatal("one SCAN MATCH needle:* COUNT 10 finished a 10003-key iteration")
+	}
+	if examined > 10+largest {
+		t.Errorf("SCAN COUNT 10 examined %d keys, want at most %d", examined, 10+largest)
+	}
+}
+
+func TestScanReturnsEveryKeyOnce(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	fillStore(t, s, 5000)
+	seen := make(map[string]int)
+	cursor := uint64(0)
+	for {
+		var keys []string
+		cursor, keys = s.Scan(ctx, cursor, "*", 100)
+		for _, key := range keys {
+			seen[key]++
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 5000 {
+		t.Fatalf("SCAN returned %d distinct keys, want 5000", len(seen))
+	}
+	for key, n := range seen {
+		if n != 1 {
+			t.Fatalf("%s returned %d times", key, n)
+		}
+	}
+}
+
+func TestScanSurvivesGrowth(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	fillStore(t, s, 1000)
+	seen := make(map[string]bool)
+	cursor, added := uint64(0), 0
+	for {
+		var keys []string
+		cursor, keys = s.Scan(ctx, cursor, "key:*", 50)
+		for _, key := range keys {
+			seen[key] = true
+		}
+		if cursor == 0 {
+			break
+		}
+		for i := 0; i < 500 && added < 5000; i++ {
+			s.Set(ctx, "new:"+strconv.Itoa(added), "v")
+			added++
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		if key := "key:" + strconv.Itoa(i); !seen[key] {
+			t.Fatalf("%s was present throughout but never returned", key)
+		}
+	}
+}
+
+func TestScanIndexShrinks(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	fillStore(t, s, 10000)
+	grown := len(s.index.buckets)
+	for i := 0; i < 9990; i++ {
+		s.Del(ctx, "key:"+strconv.Itoa(i))
+	}
+	s.Shrink(ctx)
+	if len(s.index.buckets) >= grown {
+		t.Errorf("index kept %d buckets after shrinking from %d keys to 10", len(s.index.buckets), 10000)
+	}
+	cursor, keys := uint64(0), 0
+	for {
+		var batch []string
+		cursor, batch = s.Scan(ctx, cursor, "*", 10)
+		keys += len(batch)
+		if cursor == 0 {
+			break
+		}
+	}
+	if keys != 10 {
+		t.Errorf("SCAN after shrinking returned %d keys, want 10", keys)
+	}
+}
+
+func TestScanWithConcurrentChurn(t *testing.T) {
+	for seed := uint64(1); seed <= 5; seed++ {
+		t.Run(strconv.FormatUint(seed, 10), func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := newTestStore(t, StoreOption{})
+			fillStore(t, s, 2000)
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				rng := rand.New(rand.NewPCG(seed, seed))
+				for i := 0; i < 20000; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					key := "churn:" + strconv.Itoa(rng.IntN(5000))
+					switch rng.IntN(10) {
+					case 0:
+						s.Shrink(ctx)
+					case 1, 2, 3:
+						s.Del(ctx, key)
+					default:
+						s.Set(ctx, key, "v")
+					}
+				}
+			}()
+			seen := make(map[string]bool)
+			cursor := uint64(0)
+			for {
+				var keys []string
+				cursor, keys = s.Scan(ctx, cursor, "*", 1+int(seed)*20)
+				for _, key := range keys {
+					seen[key] = true
+				}
+				if cursor == 0 {
+					break
+				}
+			}
+			close(stop)
+			<-done
+			for i := 0; i < 2000; i++ {
+				if key := "key:" + strconv.Itoa(i); !seen[key] {
+					t.Fatalf("%s was present throughout but never returned", key)
+				}
+			}
+		})
+	}
+}
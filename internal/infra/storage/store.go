@@ -2,98 +2,341 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
 	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
 	"github.com/alexsandroveiga/redis-like-golang/internal/domain/repository"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/clock"
 )
 
+type ExpireHook func(key string)
+
+type EventHook func(event, key string)
+
+const (
+	itemOverhead        = 64
+	intValueSize        = 8
+	rawValueOverhead    = 16
+	defaultMaxValueSize = 512 * 1024 * 1024
+	shrinkMinPeak       = 1024
+	shrinkLoadFactor    = 4
+	ctxCheckInterval    = 1024
+)
+
+type StoreOption struct {
+	KeysLimit         int
+	MaxMemory         int64
+	MaxValueSize      int
+	CopyOnWrite       bool
+	Clock             clock.Clock
+	CompressThreshold int
+}
+
 type Store struct {
 	data        map[string]*entity.Item
 	mu          sync.RWMutex
 	stopCleanup chan struct{}
+	resetTicker chan struct{}
+	interval    atomic.Int64
+	noActive    atomic.Bool
+	onExpire    ExpireHook
+	onEvent     EventHook
+	keysLimit   atomic.Int64
+	maxMemory   int64
+	usedMemory  int64
+	maxValue    int
+	cow         bool
+	dirty       bool
+	snapshot    atomic.Pointer[map[string]entity.Item]
+	index       *scanIndex
+	clock       clock.Clock
+	peak        int
+	compressMin int
 }
 
-func NewStore() repository.KeyValueRepository {
-	return &Store{
+func NewStore(opt StoreOption) repository.KeyValueRepository {
+	maxValue := opt.MaxValueSize
+	if maxValue <= 0 {
+		maxValue = defaultMaxValueSize
+	}
+	clk := opt.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	s := &Store{
 		data:        make(map[string]*entity.Item),
 		stopCleanup: make(chan struct{}),
+		resetTicker: make(chan struct{}, 1),
+		maxMemory:   opt.MaxMemory,
+		maxValue:    maxValue,
+		cow:         opt.CopyOnWrite,
+		index:       newScanIndex(),
+		clock:       clk,
+		compressMin: opt.CompressThreshold,
+	}
+	s.keysLimit.Store(int64(opt.KeysLimit))
+	if s.cow {
+		s.publish()
 	}
+	return s
 }
 
-func (s *Store) Set(ctx context.Context, key string, value string) {
+func (s *Store) Set(ctx context.Context, key string, value string) error {
 	if ctx.Err() != nil {
-		return
+		return ctx.Err()
+	}
+	if err := s.checkValueSize(value); err != nil {
+		return err
 	}
+	hook, err := s.set(key, value)
+	if err != nil {
+		return err
+	}
+	emit(hook, entity.EventSet, key)
+	return nil
+}
+
+// set and the other lowercase write helpers hold the lock with a deferred
+// unlock, so a panic under the lock cannot leave the store locked forever.
+// They return the hooks to fire, which must run after the lock is released.
+func (s *Store) set(key, value string) (EventHook, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.data[key] = &entity.Item{Value: value, ExpiresAt: nil}
+	defer s.unlock()
+	if err := s.checkMemory(); err != nil {
+		return nil, err
+	}
+	s.put(key, s.newItem(value))
+	return s.onEvent, nil
 }
 
-func (s *Store) Get(ctx context.Context, key string) (string, bool) {
+// SetWithOptions stores value honouring SET's NX, XX, KEEPTTL and expire
+// options. It reports false without writing when an NX or XX condition fails.
+func (s *Store) SetWithOptions(ctx context.Context, key, value string, opt command.SetOptions) (bool, error) {
 	if ctx.Err() != nil {
-		return "", false
+		return false, ctx.Err()
 	}
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	item, exists := s.data[key]
-	if !exists {
-		return "", false
+	if err := s.checkValueSize(value); err != nil {
+		return false, err
+	}
+	hook, written, err := s.setWithOptions(key, value, opt)
+	if !written {
+		return false, err
+	}
+	emit(hook, entity.EventSet, key)
+	if opt.TTLMillis > 0 {
+		emit(hook, entity.EventExpire, key)
+	}
+	return true, nil
+}
+
+func (s *Store) setWithOptions(key, value string, opt command.SetOptions) (EventHook, bool, error) {
+	s.mu.Lock()
+	defer s.unlock()
+	if err := s.checkMemory(); err != nil {
+		return nil, false, err
+	}
+	now := s.now()
+	old, exists := s.data[key]
+	if exists && old.IsExpired(now) {
+		exists = false
+	}
+	if (opt.NX && exists) || (opt.XX && !exists) {
+		return nil, false, nil
+	}
+	item := s.newItem(value)
+	switch {
+	case opt.TTLMillis > 0:
+		expiresAt := expiresAt(now, opt.TTLMillis)
+		item.ExpiresAt = &expiresAt
+	case opt.KeepTTL && exists:
+		item.ExpiresAt = old.ExpiresAt
+	}
+	s.put(key, item)
+	return s.onEvent, true, nil
+}
+
+func (s *Store) SetMany(ctx context.Context, items map[string]string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for _, value := range items {
+		if err := s.checkValueSize(value); err != nil {
+			return err
+		}
+	}
+	hook, err := s.setMany(items)
+	if err != nil {
+		return err
+	}
+	for key := range items {
+		emit(hook, entity.EventSet, key)
 	}
-	if item.IsExpired(time.Now().Unix()) {
+	return nil
+}
+
+func (s *Store) setMany(items map[string]string) (EventHook, error) {
+	s.mu.Lock()
+	defer s.unlock()
+	if err := s.checkMemory(); err != nil {
+		return nil, err
+	}
+	for key, value := range items {
+		s.put(key, s.newItem(value))
+	}
+	return s.onEvent, nil
+}
+
+func (s *Store) CompareAndSet(ctx context.Context, key, expected, value string) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err := s.checkValueSize(value); err != nil {
+		return false, err
+	}
+	hook, swapped, err := s.compareAndSet(key, expected, value)
+	if !swapped {
+		return false, err
+	}
+	emit(hook, entity.EventSet, key)
+	return true, nil
+}
+
+func (s *Store) compareAndSet(key, expected, value string) (EventHook, bool, error) {
+	s.mu.Lock()
+	defer s.unlock()
+	if err := s.checkMemory(); err != nil {
+		return nil, false, err
+	}
+	current := ""
+	if item, exists := s.data[key]; exists && !item.IsExpired(s.now()) {
+		current = itemValue(item)
+	}
+	if current != expected {
+		return nil, false, nil
+	}
+	s.put(key, s.newItem(value))
+	return s.onEvent, true, nil
+}
+
+func (s *Store) Get(ctx context.Context, key string) (string, bool) {
+	if ctx.Err() != nil {
 		return "", false
 	}
-	return item.Value, true
+	var value string
+	exists := s.readLive(key, func(item *entity.Item) {
+		value = itemValue(item)
+	})
+	return value, exists
 }
 
 func (s *Store) Del(ctx context.Context, key string) int {
 	if ctx.Err() != nil {
 		return 0
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, exists := s.data[key]; exists {
-		delete(s.data, key)
-		return 1
+	hook, exists := s.del(key)
+	if !exists {
+		return 0
 	}
-	return 0
+	emit(hook, entity.EventDel, key)
+	return 1
+}
+
+func (s *Store) del(key string) (EventHook, bool) {
+	s.mu.Lock()
+	defer s.unlock()
+	return s.onEvent, s.remove(key)
 }
 
 func (s *Store) Expire(ctx context.Context, key string, durationInSeconds int) bool {
+	seconds := int64(durationInSeconds)
+	ms := int64(math.MaxInt64)
+	switch {
+	case seconds <= 0:
+		ms = seconds
+	case seconds <= math.MaxInt64/1000:
+		ms = seconds * 1000
+	}
+	return s.PExpire(ctx, key, ms)
+}
+
+// PExpire sets a TTL of ms milliseconds on key. Like EXPIRE, a non-positive
+// TTL deletes the key at once.
+func (s *Store) PExpire(ctx context.Context, key string, ms int64) bool {
 	if ctx.Err() != nil {
 		return false
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	item, exists := s.data[key]
-	if !exists {
+	r := s.expire(key, ms)
+	if r.expired {
+		notifyExpired(r.expireHook, []string{key})
+	}
+	if !r.exists {
 		return false
 	}
-	expiresAt := time.Now().Unix() + int64(durationInSeconds)
-	item.ExpiresAt = &expiresAt
+	if r.deleted {
+		emit(r.hook, entity.EventDel, key)
+		return true
+	}
+	emit(r.hook, entity.EventExpire, key)
 	return true
 }
 
-func (s *Store) TTL(ctx context.Context, key string) int64 {
-	if ctx.Err() != nil {
-		return -1
-	}
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+type expireResult struct {
+	hook                     EventHook
+	expireHook               ExpireHook
+	exists, expired, deleted bool
+}
+
+func (s *Store) expire(key string, ms int64) expireResult {
+	s.mu.Lock()
+	defer s.unlock()
+	now := s.now()
+	r := expireResult{hook: s.onEvent, expireHook: s.onExpire}
 	item, exists := s.data[key]
-	if !exists {
-		return -1
+	r.expired = exists && item.IsExpired(now)
+	if r.expired {
+		s.remove(key)
+		return r
 	}
-	if item.ExpiresAt == nil {
+	r.exists = exists
+	r.deleted = exists && ms <= 0
+	if r.deleted {
+		s.remove(key)
+	} else if exists {
+		expiresAt := expiresAt(now, ms)
+		item.ExpiresAt = &expiresAt
+		s.dirty = true
+	}
+	return r
+}
+
+// TTL returns the remaining time to live of key in seconds, rounded to the
+// nearest second, or -1 when it is missing or has no expiry.
+func (s *Store) TTL(ctx context.Context, key string) int64 {
+	if ctx.Err() != nil {
 		return -1
 	}
-	now := time.Now().Unix()
-	remaining := *item.ExpiresAt - now
-	if remaining <= 0 {
+	remaining := int64(-1)
+	s.readLive(key, func(item *entity.Item) {
+		remaining = remainingTTL(item, s.now())
+	})
+	return remaining
+}
+
+// PTTL is TTL in milliseconds.
+func (s *Store) PTTL(ctx context.Context, key string) int64 {
+	if ctx.Err() != nil {
 		return -1
 	}
+	remaining := int64(-1)
+	s.readLive(key, func(item *entity.Item) {
+		remaining = remainingMillis(item, s.now())
+	})
 	return remaining
 }
 
@@ -101,47 +344,121 @@ func (s *Store) Persist(ctx context.Context, key string) bool {
 	if ctx.Err() != nil {
 		return false
 	}
+	hook, cleared := s.persist(key)
+	if !cleared {
+		return false
+	}
+	emit(hook, entity.EventPersist, key)
+	return true
+}
+
+func (s *Store) persist(key string) (EventHook, bool) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 	item, exists := s.data[key]
-	if !exists {
-		return false
+	if !exists || item.ExpiresAt == nil || item.IsExpired(s.now()) {
+		return nil, false
 	}
 	item.ExpiresAt = nil
-	return true
+	s.dirty = true
+	return s.onEvent, true
+}
+
+// Keys returns the live keys matching pattern in no particular order. The
+// slice is freshly allocated and owned by the caller. When more keys match
+// than the keys limit allows, Keys returns no keys and an error pointing the
+// caller at SCAN.
+func (s *Store) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if ctx.Err() != nil {
+		return []string{}, ctx.Err()
+	}
+	return s.appendKeys(ctx, pattern, nil, int(s.keysLimit.Load()))
 }
 
-func (s *Store) Keys(ctx context.Context, pattern string) []string {
+// KeysInto is Keys for hot internal paths: it appends the matches to
+// buf[:0], reusing its capacity, and is not subject to KeysLimit.
+func (s *Store) KeysInto(ctx context.Context, pattern string, buf []string) []string {
 	if ctx.Err() != nil {
-		return []string{}
+		return buf[:0]
 	}
+	matches, err := s.appendKeys(ctx, pattern, buf[:0], 0)
+	if err != nil {
+		return buf[:0]
+	}
+	return matches
+}
+
+func (s *Store) appendKeys(ctx context.Context, pattern string, matches []string, limit int) ([]string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	now := time.Now().Unix()
-	var matches []string
+	now := s.now()
+	examined := 0
 	for key, item := range s.data {
+		examined++
+		if examined%ctxCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if item.IsExpired(now) {
 			continue
 		}
 		if matchPattern(key, pattern) {
+			if limit > 0 && len(matches) >= limit {
+				return nil, fmt.Errorf("KEYS matched more than %d keys, use SCAN or a narrower pattern", limit)
+			}
 			matches = append(matches, key)
 		}
 	}
-	return matches
+	return matches, nil
 }
 
-func (s *Store) Exists(ctx context.Context, key string) bool {
+func (s *Store) CountKeys(ctx context.Context, pattern string, limit int) int {
 	if ctx.Err() != nil {
-		return false
+		return 0
 	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	item, exists := s.data[key]
-	if !exists {
+	now := s.now()
+	count := 0
+	examined := 0
+	for key, item := range s.data {
+		if limit > 0 && count >= limit {
+			break
+		}
+		examined++
+		if examined%ctxCheckInterval == 0 && ctx.Err() != nil {
+			break
+		}
+		if !item.IsExpired(now) && matchPattern(key, pattern) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Store) SnapshotKeysWithTTL(ctx context.Context) map[string]int64 {
+	if ctx.Err() != nil {
+		return map[string]int64{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	snapshot := make(map[string]int64, len(s.data))
+	for key, item := range s.data {
+		if item.IsExpired(now) {
+			continue
+		}
+		snapshot[key] = remainingTTL(item, now)
+	}
+	return snapshot
+}
+
+func (s *Store) Exists(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
 		return false
 	}
-	return !item.IsExpired(time.Now().Unix())
+	return s.readLive(key, nil)
 }
+
 func (s *Store) Size(ctx context.Context) int {
 	if ctx.Err() != nil {
 		return 0
@@ -151,34 +468,469 @@ func (s *Store) Size(ctx context.Context) int {
 	return len(s.data)
 }
 
+// KeyspaceStats counts live keys and those with an expiry. AvgTTL is the mean
+// remaining TTL in milliseconds of the keys with an expiry.
+func (s *Store) KeyspaceStats(ctx context.Context) repository.KeyspaceStats {
+	var stats repository.KeyspaceStats
+	if ctx.Err() != nil {
+		return stats
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	var totalTTL int64
+	for _, item := range s.data {
+		if item.IsExpired(now) {
+			continue
+		}
+		stats.Keys++
+		if item.ExpiresAt != nil {
+			stats.Expires++
+			totalTTL += *item.ExpiresAt - now
+		}
+	}
+	if stats.Expires > 0 {
+		stats.AvgTTL = totalTTL / int64(stats.Expires)
+	}
+	return stats
+}
+
+// SetMaxMemory changes the memory limit. Under the noeviction policy a limit
+// below current usage takes effect by rejecting the next write with OOM.
+func (s *Store) SetMaxMemory(bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxMemory = bytes
+}
+
+func (s *Store) MaxMemory() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxMemory
+}
+
+// SetKeysLimit caps how many keys a single Keys call may return; 0 removes
+// the cap.
+func (s *Store) SetKeysLimit(limit int) {
+	s.keysLimit.Store(int64(limit))
+}
+
+func (s *Store) KeysLimit() int {
+	return int(s.keysLimit.Load())
+}
+
+func (s *Store) UsedMemory(ctx context.Context) int64 {
+	if ctx.Err() != nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usedMemory
+}
+
+// FlushAll swaps in an empty keyspace in constant time. Nothing walks the old
+// map: once unreachable, the concurrent garbage collector reclaims it off
+// the request path, which is all Redis's lazy free buys.
+func (s *Store) FlushAll(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.unlock()
+	s.data = make(map[string]*entity.Item)
+	s.index = newScanIndex()
+	s.usedMemory = 0
+	s.peak = 0
+	s.dirty = true
+}
+
+// Restore replaces the keyspace with items in one step, so readers see either
+// the old data or the new. expiresAt holds absolute expiries in Unix
+// milliseconds. As when Redis loads a dump, maxmemory is not enforced.
+func (s *Store) Restore(ctx context.Context, items map[string]string, expiresAt map[string]int64) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.unlock()
+	s.data = make(map[string]*entity.Item, len(items))
+	s.index = newScanIndex()
+	s.usedMemory = 0
+	s.peak = 0
+	for key, value := range items {
+		item := s.newItem(value)
+		if at, exists := expiresAt[key]; exists {
+			item.ExpiresAt = &at
+		}
+		s.put(key, item)
+	}
+	s.dirty = true
+	return nil
+}
+
+func (s *Store) Shrink(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rebuild()
+}
+
 func (s *Store) StartCleanup(intervalInMs int64) {
-	interval := time.Duration(intervalInMs) * time.Millisecond
+	s.interval.Store(intervalInMs)
 	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
+		var ticker *time.Ticker
+		var tick <-chan time.Time
+		reset := func() {
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+			if ms := s.interval.Load(); ms > 0 {
+				ticker = time.NewTicker(time.Duration(ms) * time.Millisecond)
+				tick = ticker.C
+			}
+		}
+		reset()
 		for {
 			select {
-			case <-ticker.C:
-				s.cleanupExpired()
+			case <-tick:
+				if !s.noActive.Load() {
+					s.cleanupExpired()
+				}
+			case <-s.resetTicker:
+				reset()
 			case <-s.stopCleanup:
+				if ticker != nil {
+					ticker.Stop()
+				}
+				return
 			}
 		}
 	}()
 }
 
+// SetCleanupInterval changes the active expiry cadence of a running cleanup
+// loop. Zero disables active expiry, leaving only lazy deletion on access.
+func (s *Store) SetCleanupInterval(intervalInMs int64) {
+	s.interval.Store(intervalInMs)
+	select {
+	case s.resetTicker <- struct{}{}:
+	default:
+	}
+}
+
+// SetActiveExpire pauses or resumes the background cleanup without stopping
+// its ticker. Lazy deletion on access is unaffected.
+func (s *Store) SetActiveExpire(enabled bool) {
+	s.noActive.Store(!enabled)
+}
+
+func (s *Store) CleanupInterval() int64 {
+	return s.interval.Load()
+}
+
 func (s *Store) StopCleanup() {
 	close(s.stopCleanup)
 }
 
-func (s *Store) cleanupExpired() {
+func (s *Store) OnExpire(hook func(key string)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	now := time.Now().Unix()
+	s.onExpire = hook
+}
+
+func (s *Store) OnEvent(hook func(event, key string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEvent = hook
+}
+
+func (s *Store) readLive(key string, read func(item *entity.Item)) bool {
+	if s.cow {
+		return s.readSnapshot(key, read)
+	}
+	found, expired := s.readLocked(key, read)
+	if expired != nil {
+		s.deleteExpired(key, expired)
+	}
+	return found
+}
+
+// readLocked serves readLive under the read lock. It returns the item
+// instead of deleting it when it has expired, since that needs the write
+// lock.
+func (s *Store) readLocked(key string, read func(item *entity.Item)) (bool, *entity.Item) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, exists := s.data[key]
+	if !exists {
+		return false, nil
+	}
+	if item.IsExpired(s.now()) {
+		return false, item
+	}
+	item.Touch(s.now())
+	if read != nil {
+		read(item)
+	}
+	return true, nil
+}
+
+// Object inspects the stored item without counting as an access. IdleTime is
+// how many seconds ago key was last read or written; reads served from
+// copy-on-write snapshots are lock-free and do not refresh it.
+func (s *Store) Object(ctx context.Context, key string) (repository.ObjectInfo, bool) {
+	if ctx.Err() != nil {
+		return repository.ObjectInfo{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	item, exists := s.data[key]
+	if !exists || item.IsExpired(now) {
+		return repository.ObjectInfo{}, false
+	}
+	return repository.ObjectInfo{
+		Encoding:     item.Encoding(),
+		StoredLength: len(item.Value),
+		IdleTime:     item.IdleTime(now),
+		MemoryUsage:  itemSize(key, item),
+	}, true
+}
+
+// Peek returns key's value and absolute expiry in Unix milliseconds, zero for
+// none, read together without counting as an access.
+func (s *Store) Peek(ctx context.Context, key string) (string, int64, bool) {
+	if ctx.Err() != nil {
+		return "", 0, false
+	}
+	s.mu.RLock()
+	item, exists := s.data[key]
+	if !exists || item.IsExpired(s.now()) {
+		s.mu.RUnlock()
+		return "", 0, false
+	}
+	stored := entity.Item{Value: item.Value, Compressed: item.Compressed}
+	expiresAt := int64(0)
+	if item.ExpiresAt != nil {
+		expiresAt = *item.ExpiresAt
+	}
+	s.mu.RUnlock()
+	return itemValue(&stored), expiresAt, true
+}
+
+func (s *Store) readSnapshot(key string, read func(item *entity.Item)) bool {
+	snapshot := *s.snapshot.Load()
+	item, exists := snapshot[key]
+	if !exists {
+		return false
+	}
+	if item.IsExpired(s.now()) {
+		s.deleteExpired(key, nil)
+		return false
+	}
+	if read != nil {
+		read(&item)
+	}
+	return true
+}
+
+func (s *Store) deleteExpired(key string, observed *entity.Item) {
+	if hook, deleted := s.removeExpired(key, observed); deleted {
+		notifyExpired(hook, []string{key})
+	}
+}
+
+func (s *Store) removeExpired(key string, observed *entity.Item) (ExpireHook, bool) {
+	s.mu.Lock()
+	defer s.unlock()
+	item, exists := s.data[key]
+	if !exists || (observed != nil && item != observed) || !item.IsExpired(s.now()) {
+		return nil, false
+	}
+	s.remove(key)
+	return s.onExpire, true
+}
+
+// CleanupNow runs an active expiry pass immediately and returns how many
+// keys it removed.
+func (s *Store) CleanupNow(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	return s.cleanupExpired()
+}
+
+func (s *Store) cleanupExpired() int {
+	hook, expired := s.removeAllExpired()
+	notifyExpired(hook, expired)
+	return len(expired)
+}
+
+func (s *Store) removeAllExpired() (ExpireHook, []string) {
+	s.mu.Lock()
+	defer s.unlock()
+	now := s.now()
+	var expired []string
 	for key, item := range s.data {
 		if item.IsExpired(now) {
-			delete(s.data, key)
+			s.remove(key)
+			expired = append(expired, key)
 		}
 	}
+	if s.peak >= shrinkMinPeak && len(s.data)*shrinkLoadFactor < s.peak {
+		s.rebuild()
+	}
+	return s.onExpire, expired
+}
+
+// rebuild copies the live entries into a freshly sized map, and shrinks the
+// scan index to match, so the buckets left behind by deleted keys can be
+// garbage collected.
+func (s *Store) rebuild() {
+	data := make(map[string]*entity.Item, len(s.data))
+	for key, item := range s.data {
+		data[key] = item
+	}
+	s.data = data
+	s.peak = len(data)
+	s.index.fit()
+}
+
+// now returns the clock's Unix time in milliseconds, the resolution of
+// expiry and access times.
+func (s *Store) now() int64 {
+	return s.clock.Now().UnixMilli()
+}
+
+func (s *Store) unlock() {
+	if s.cow && s.dirty {
+		s.publish()
+		s.dirty = false
+	}
+	s.mu.Unlock()
+}
+
+func (s *Store) publish() {
+	snapshot := make(map[string]entity.Item, len(s.data))
+	for key, item := range s.data {
+		snapshot[key] = *item
+	}
+	s.snapshot.Store(&snapshot)
+}
+
+func (s *Store) checkMemory() error {
+	if s.maxMemory > 0 && s.usedMemory > s.maxMemory {
+		return entity.ErrOOM
+	}
+	return nil
+}
+
+func (s *Store) checkValueSize(value string) error {
+	if len(value) > s.maxValue {
+		return entity.ErrValueTooLarge
+	}
+	return nil
+}
+
+func (s *Store) newItem(value string) *entity.Item {
+	item := &entity.Item{Value: value, ExpiresAt: nil}
+	if s.compressMin > 0 && len(value) >= s.compressMin {
+		item.Value, item.Compressed = compressValue(value)
+	}
+	return item
+}
+
+func (s *Store) put(key string, item *entity.Item) {
+	if old, exists := s.data[key]; exists {
+		s.usedMemory -= itemSize(key, old)
+	} else {
+		s.index.add(key)
+	}
+	item.Touch(s.now())
+	s.data[key] = item
+	s.peak = max(s.peak, len(s.data))
+	s.usedMemory += itemSize(key, item)
+	s.dirty = true
+}
+
+func (s *Store) update(item *entity.Item, value string) {
+	s.usedMemory += valueSize(value) - valueSize(item.Value)
+	item.Value = value
+	item.Compressed = false
+	item.Touch(s.now())
+	s.dirty = true
+}
+
+func (s *Store) remove(key string) bool {
+	item, exists := s.data[key]
+	if !exists {
+		return false
+	}
+	delete(s.data, key)
+	s.index.remove(key)
+	s.usedMemory -= itemSize(key, item)
+	s.dirty = true
+	return true
+}
+
+func itemSize(key string, item *entity.Item) int64 {
+	return int64(len(key)+itemOverhead+scanEntrySize) + valueSize(item.Value)
+}
+
+// valueSize estimates a value's footprint by encoding: integers fit in a
+// machine word, embstr shares the item's allocation and raw pays for its own.
+func valueSize(value string) int64 {
+	switch {
+	case entity.IsIntEncodable(value):
+		return intValueSize
+	case len(value) <= entity.EmbstrSizeLimit:
+		return int64(len(value))
+	default:
+		return int64(len(value)) + rawValueOverhead
+	}
+}
+
+func remainingTTL(item *entity.Item, now int64) int64 {
+	left := remainingMillis(item, now)
+	if left < 0 {
+		return left
+	}
+	return (left + 500) / 1000
+}
+
+func remainingMillis(item *entity.Item, now int64) int64 {
+	if item.ExpiresAt == nil {
+		return -1
+	}
+	return max(*item.ExpiresAt-now, 0)
+}
+
+// expiresAt returns the absolute expiry ms after now, saturating instead of
+// overflowing.
+func expiresAt(now, ms int64) int64 {
+	if ms > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + ms
+}
+
+func notifyExpired(hook ExpireHook, keys []string) {
+	if hook == nil {
+		return
+	}
+	for _, key := range keys {
+		hook(key)
+	}
+}
+
+func emit(hook EventHook, event, key string) {
+	if hook == nil {
+		return
+	}
+	hook(event, key)
 }
 
 func matchPattern(key, pattern string) bool {
@@ -0,0 +1,717 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/command"
+	"github.com/alexsandroveiga/redis-like-golang/internal/domain/entity"
+	"github.com/alexsandroveiga/redis-like-golang/internal/infra/clock"
+)
+
+func newTestStore(t *testing.T, opt StoreOption) (*Store, *clock.Mock) {
+	t.Helper()
+	clk := clock.NewMock(time.Unix(1_700_000_000, 0))
+	opt.Clock = clk
+	return NewStore(opt).(*Store), clk
+}
+
+func TestActiveExpireDisabled(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	if err := s.Set(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	s.Expire(ctx, "k", 1)
+	clk.Advance(2 * time.Second)
+
+	s.SetActiveExpire(false)
+	s.StartCleanup(1)
+	defer s.StopCleanup()
+	time.Sleep(20 * time.Millisecond)
+	if size := s.Size(ctx); size != 1 {
+		t.Fatalf("Size with active expire off = %d, want the expired key kept", size)
+	}
+	if _, exists := s.Get(ctx, "k"); exists {
+		t.Fatal("GET returned an expired key")
+	}
+	if size := s.Size(ctx); size != 0 {
+		t.Errorf("Size after lazy expiry = %d, want 0", size)
+	}
+}
+
+func TestKeysLimit(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{KeysLimit: 2})
+	for _, key := range []string{"a", "b", "c"} {
+		if err := s.Set(ctx, key, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys, err := s.Keys(ctx, "*"); err == nil {
+		t.Fatalf("Keys over the limit = %v, want an error", keys)
+	}
+	if keys, err := s.Keys(ctx, "a"); err != nil || len(keys) != 1 {
+		t.Fatalf("Keys under the limit = %v, %v", keys, err)
+	}
+	if keys := s.KeysInto(ctx, "*", nil); len(keys) != 3 {
+		t.Errorf("KeysInto = %v, want all 3 keys regardless of the limit", keys)
+	}
+	s.SetKeysLimit(0)
+	if keys, err := s.Keys(ctx, "*"); err != nil || len(keys) != 3 {
+		t.Errorf("Keys with the limit removed = %v, %v", keys, err)
+	}
+}
+
+func TestPanicUnderLockReleasesIt(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	if err := s.Set(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() { recover() }()
+		s.readLive("k", func(item *entity.Item) { panic("boom") })
+	}()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Set(ctx, "k", "w")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Set blocked after a panic under the read lock")
+	}
+}
+
+func benchmarkItems(n int) map[string]string {
+	items := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		items["key:"+strconv.Itoa(i)] = "value"
+	}
+	return items
+}
+
+func BenchmarkSetLoop(b *testing.B) {
+	ctx := context.Background()
+	items := benchmarkItems(1000)
+	for i := 0; i < b.N; i++ {
+		s := NewStore(StoreOption{})
+		for key, value := range items {
+			s.Set(ctx, key, value)
+		}
+	}
+}
+
+func BenchmarkSetMany(b *testing.B) {
+	ctx := context.Background()
+	items := benchmarkItems(1000)
+	for i := 0; i < b.N; i++ {
+		s := NewStore(StoreOption{})
+		s.SetMany(ctx, items)
+	}
+}
+
+func TestExpireHook(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	var expired []string
+	s.OnExpire(func(key string) {
+		// Hooks run outside the lock, so calling back into the store is safe.
+		s.Exists(ctx, key)
+		expired = append(expired, key)
+	})
+	for _, key := range []string{"lazy", "active", "kept"} {
+		s.Set(ctx, key, "v")
+	}
+	s.Expire(ctx, "lazy", 1)
+	s.Expire(ctx, "active", 1)
+	clk.Advance(2 * time.Second)
+
+	if _, exists := s.Get(ctx, "lazy"); exists {
+		t.Fatal("GET returned an expired key")
+	}
+	if n := s.CleanupNow(ctx); n != 1 {
+		t.Fatalf("CleanupNow removed %d keys, want 1", n)
+	}
+	want := []string{"lazy", "active"}
+	if !reflect.DeepEqual(expired, want) {
+		t.Errorf("expired = %v, want %v", expired, want)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	s.Set(ctx, "volatile", "v")
+	s.Expire(ctx, "volatile", 100)
+	s.Set(ctx, "persistent", "v")
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"missing", false},
+		{"persistent", false},
+		{"volatile", true},
+		{"volatile", false},
+	}
+	for _, tt := range tests {
+		if got := s.Persist(ctx, tt.key); got != tt.want {
+			t.Errorf("Persist(%s) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if ttl := s.TTL(ctx, "volatile"); ttl != -1 {
+		t.Errorf("TTL after Persist = %d, want -1", ttl)
+	}
+}
+
+func TestExpiredKeyIsNotRevived(t *testing.T) {
+	const keys = 200
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	for i := 0; i < keys; i++ {
+		key := strconv.Itoa(i)
+		if err := s.Set(ctx, key, "v"); err != nil {
+			t.Fatal(err)
+		}
+		s.Expire(ctx, key, 1)
+	}
+	clk.Advance(2 * time.Second)
+
+	var wg sync.WaitGroup
+	var revived atomic.Int64
+	for g := 0; g < 4; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				key := strconv.Itoa(i)
+				s.Get(ctx, key)
+				s.Exists(ctx, key)
+				s.TTL(ctx, key)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < keys; i++ {
+				if s.Expire(ctx, strconv.Itoa(i), 100) {
+					revived.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := revived.Load(); n != 0 {
+		t.Errorf("EXPIRE revived %d expired keys", n)
+	}
+	if size := s.Size(ctx); size != 0 {
+		t.Errorf("Size = %d, want every expired key deleted", size)
+	}
+}
+
+func TestValueSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{MaxValueSize: 8})
+	if err := s.Set(ctx, "fits", "12345678"); err != nil {
+		t.Fatalf("SET at the limit = %v", err)
+	}
+	big := "123456789"
+	writes := map[string]func() error{
+		"Set": func() error { return s.Set(ctx, "k", big) },
+		"SetWithOptions": func() error {
+			_, err := s.SetWithOptions(ctx, "k", big, command.SetOptions{})
+			return err
+		},
+		"SetMany": func() error { return s.SetMany(ctx, map[string]string{"k": big}) },
+		"CompareAndSet": func() error {
+			_, err := s.CompareAndSet(ctx, "k", "", big)
+			return err
+		},
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, entity.ErrValueTooLarge) {
+			t.Errorf("%s over the limit = %v, want ErrValueTooLarge", name, err)
+		}
+	}
+	if s.Exists(ctx, "k") {
+		t.Error("an oversized value was stored")
+	}
+}
+
+func TestCompareAndSetIsAtomic(t *testing.T) {
+	const workers, increments = 8, 200
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	if err := s.Set(ctx, "counter", "0"); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < increments; {
+				current, _ := s.Get(ctx, "counter")
+				n, _ := strconv.Atoi(current)
+				swapped, err := s.CompareAndSet(ctx, "counter", current, strconv.Itoa(n+1))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if swapped {
+					i++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _ := s.Get(ctx, "counter"); value != strconv.Itoa(workers*increments) {
+		t.Errorf("counter = %s, want %d", value, workers*increments)
+	}
+}
+
+func TestCompareAndSetMissingKey(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	if swapped, _ := s.CompareAndSet(ctx, "k", "v", "new"); swapped {
+		t.Error("CAS on a missing key matched a non-empty expected value")
+	}
+	if swapped, _ := s.CompareAndSet(ctx, "k", "", "new"); !swapped {
+		t.Error("CAS on a missing key did not match an empty expected value")
+	}
+	if value, _ := s.Get(ctx, "k"); value != "new" {
+		t.Errorf("k = %q, want new", value)
+	}
+}
+
+func TestCopyOnWriteReadsSeeWrites(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{CopyOnWrite: true})
+	if err := s.Set(ctx, "k", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := s.Get(ctx, "k"); value != "v1" {
+		t.Fatalf("k = %q, want v1", value)
+	}
+	if err := s.Set(ctx, "k", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := s.Get(ctx, "k"); value != "v2" {
+		t.Errorf("k = %q after overwrite, want v2", value)
+	}
+	s.Del(ctx, "k")
+	if s.Exists(ctx, "k") {
+		t.Error("deleted key still visible in the snapshot")
+	}
+}
+
+func BenchmarkParallelGet(b *testing.B) {
+	ctx := context.Background()
+	items := benchmarkItems(1000)
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	for _, mode := range []struct {
+		name string
+		cow  bool
+	}{{"RWMutex", false}, {"CopyOnWrite", true}} {
+		b.Run(mode.name, func(b *testing.B) {
+			s := NewStore(StoreOption{CopyOnWrite: mode.cow})
+			s.SetMany(ctx, items)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					s.Get(ctx, keys[i%len(keys)])
+				}
+			})
+		})
+	}
+}
+
+func TestExpiryFollowsTheClock(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	for _, key := range []string{"short", "long"} {
+		if err := s.Set(ctx, key, "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Expire(ctx, "short", 10)
+	s.Expire(ctx, "long", 100)
+
+	clk.Advance(9 * time.Second)
+	if ttl := s.TTL(ctx, "short"); ttl != 1 {
+		t.Errorf("TTL short = %d after 9s, want 1", ttl)
+	}
+	if !s.Exists(ctx, "short") {
+		t.Fatal("short expired early")
+	}
+	clk.Advance(2 * time.Second)
+	if _, exists := s.Get(ctx, "short"); exists {
+		t.Error("short still readable past its expiry time")
+	}
+	if ttl := s.TTL(ctx, "long"); ttl != 89 {
+		t.Errorf("TTL long = %d after 11s, want 89", ttl)
+	}
+
+	clk.Advance(90 * time.Second)
+	if n := s.CleanupNow(ctx); n != 1 {
+		t.Errorf("CleanupNow removed %d keys, want only long", n)
+	}
+	if size := s.Size(ctx); size != 0 {
+		t.Errorf("Size = %d, want 0", size)
+	}
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+func TestShrinkReleasesDeletedBuckets(t *testing.T) {
+	const keys = 100_000
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	if err := s.SetMany(ctx, benchmarkItems(keys)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < keys*9/10; i++ {
+		s.Del(ctx, "key:"+strconv.Itoa(i))
+	}
+	before := heapInUse()
+	s.Shrink(ctx)
+	after := heapInUse()
+	if s.peak != keys/10 {
+		t.Errorf("peak = %d after Shrink, want the %d live keys", s.peak, keys/10)
+	}
+	if after >= before {
+		t.Errorf("heap in use %d after Shrink, want less than %d", after, before)
+	}
+	if size := s.Size(ctx); size != keys/10 {
+		t.Errorf("Size = %d after Shrink, want %d", size, keys/10)
+	}
+}
+
+func TestCleanupShrinksAfterMassExpiry(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	if err := s.SetMany(ctx, benchmarkItems(shrinkMinPeak*shrinkLoadFactor)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < shrinkMinPeak*shrinkLoadFactor-10; i++ {
+		s.Expire(ctx, "key:"+strconv.Itoa(i), 1)
+	}
+	clk.Advance(2 * time.Second)
+	s.CleanupNow(ctx)
+	if s.peak != 10 {
+		t.Errorf("peak = %d after mass expiry, want the map rebuilt for 10 keys", s.peak)
+	}
+}
+
+// expireNow adds key with a TTL that has already run out on clk.
+func expireNow(t *testing.T, s *Store, clk *clock.Mock, key string) {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.Set(ctx, key, "v"); err != nil {
+		t.Fatal(err)
+	}
+	s.Expire(ctx, key, 1)
+	clk.Advance(2 * time.Second)
+}
+
+func TestSetCleanupInterval(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	s.StartCleanup(0)
+	defer s.StopCleanup()
+
+	expireNow(t, s, clk, "a")
+	time.Sleep(20 * time.Millisecond)
+	if size := s.Size(ctx); size != 1 {
+		t.Fatalf("Size = %d with cleanup disabled, want the expired key kept", size)
+	}
+
+	s.SetCleanupInterval(1)
+	if got := s.CleanupInterval(); got != 1 {
+		t.Errorf("CleanupInterval = %d, want 1", got)
+	}
+	deadline := time.Now().Add(time.Second)
+	for s.Size(ctx) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired key not removed after enabling a 1ms cleanup interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	s.SetCleanupInterval(0)
+	time.Sleep(5 * time.Millisecond)
+	expireNow(t, s, clk, "b")
+	time.Sleep(20 * time.Millisecond)
+	if size := s.Size(ctx); size != 1 {
+		t.Errorf("Size = %d after disabling cleanup again, want the expired key kept", size)
+	}
+}
+
+func TestIntEncodedValue(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	value := "1234567890123456789"
+	if err := s.Set(ctx, "int", value); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, "str", value+"x"); err != nil {
+		t.Fatal(err)
+	}
+	num, _ := s.Object(ctx, "int")
+	str, _ := s.Object(ctx, "str")
+	if num.Encoding != entity.EncodingInt || str.Encoding != entity.EncodingEmbstr {
+		t.Fatalf("encodings = %s, %s; want int, embstr", num.Encoding, str.Encoding)
+	}
+	if num.MemoryUsage >= str.MemoryUsage-1 {
+		t.Errorf("MEMORY USAGE int = %d, want well under embstr's %d", num.MemoryUsage, str.MemoryUsage)
+	}
+}
+
+func TestCountKeys(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	if err := s.SetMany(ctx, benchmarkItems(100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, "other", "v"); err != nil {
+		t.Fatal(err)
+	}
+	expireNow(t, s, clk, "key:expired")
+	tests := []struct {
+		pattern string
+		limit   int
+		want    int
+	}{
+		{"key:*", 0, 100},
+		{"key:*", 5, 5},
+		{"key:*", 1000, 100},
+		{"*", 0, 101},
+		{"other", 5, 1},
+		{"missing", 5, 0},
+	}
+	for _, tt := range tests {
+		if got := s.CountKeys(ctx, tt.pattern, tt.limit); got != tt.want {
+			t.Errorf("CountKeys(%q, %d) = %d, want %d", tt.pattern, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkCountKeys(b *testing.B) {
+	ctx := context.Background()
+	s := NewStore(StoreOption{})
+	s.SetMany(ctx, benchmarkItems(100_000))
+	for _, limit := range []int{0, 10} {
+		b.Run("limit="+strconv.Itoa(limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.CountKeys(ctx, "key:*", limit)
+			}
+		})
+	}
+}
+
+func TestExpireNonPositiveDeletes(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	var events []string
+	s.OnEvent(func(event, key string) { events = append(events, event) })
+	for _, seconds := range []int{-5, 0} {
+		if err := s.Set(ctx, "k", "v"); err != nil {
+			t.Fatal(err)
+		}
+		events = nil
+		if !s.Expire(ctx, "k", seconds) {
+			t.Errorf("EXPIRE k %d = false on an existing key", seconds)
+		}
+		if size := s.Size(ctx); size != 0 {
+			t.Errorf("EXPIRE k %d left the key in the map", seconds)
+		}
+		if _, exists := s.Get(ctx, "k"); exists {
+			t.Errorf("GET after EXPIRE k %d found the key", seconds)
+		}
+		if !reflect.DeepEqual(events, []string{entity.EventDel}) {
+			t.Errorf("events after EXPIRE k %d = %v, want a single del", seconds, events)
+		}
+	}
+	if s.Expire(ctx, "missing", -5) {
+		t.Error("EXPIRE missing -5 = true, want false")
+	}
+}
+
+func TestIdleTime(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	if err := s.Set(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	idle := func() int64 {
+		t.Helper()
+		info, exists := s.Object(ctx, "k")
+		if !exists {
+			t.Fatal("k missing")
+		}
+		return info.IdleTime
+	}
+	if got := idle(); got != 0 {
+		t.Errorf("IDLETIME right after SET = %d, want 0", got)
+	}
+	clk.Advance(30 * time.Second)
+	if got := idle(); got != 30 {
+		t.Errorf("IDLETIME after 30s untouched = %d, want 30", got)
+	}
+	if got := idle(); got != 30 {
+		t.Errorf("OBJECT counted as an access: IDLETIME = %d, want 30", got)
+	}
+	s.Get(ctx, "k")
+	if got := idle(); got != 0 {
+		t.Errorf("IDLETIME after GET = %d, want 0", got)
+	}
+	clk.Advance(5 * time.Second)
+	if err := s.Set(ctx, "k", "w"); err != nil {
+		t.Fatal(err)
+	}
+	if got := idle(); got != 0 {
+		t.Errorf("IDLETIME after SET = %d, want 0", got)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	if err := s.Set(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	s.PExpire(ctx, "k", 1500)
+	want := clk.Now().UnixMilli() + 1500
+	clk.Advance(time.Second)
+	value, expiresAt, exists := s.Peek(ctx, "k")
+	if !exists || value != "v" || expiresAt != want {
+		t.Errorf("Peek = %q, %d, %v; want v, %d, true", value, expiresAt, exists, want)
+	}
+	if info, _ := s.Object(ctx, "k"); info.IdleTime != 1 {
+		t.Errorf("Peek counted as an access: IDLETIME = %d, want 1", info.IdleTime)
+	}
+	if _, expiresAt, _ := s.Peek(ctx, "missing"); expiresAt != 0 {
+		t.Errorf("Peek missing expiresAt = %d, want 0", expiresAt)
+	}
+	clk.Advance(time.Second)
+	if _, _, exists := s.Peek(ctx, "k"); exists {
+		t.Error("Peek returned an expired key")
+	}
+}
+
+func TestCleanupNow(t *testing.T) {
+	ctx := context.Background()
+	s, clk := newTestStore(t, StoreOption{})
+	if err := s.SetMany(ctx, benchmarkItems(10)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		s.Expire(ctx, "key:"+strconv.Itoa(i), 1)
+	}
+	var expired []string
+	s.OnExpire(func(key string) { expired = append(expired, key) })
+	if n := s.CleanupNow(ctx); n != 0 {
+		t.Fatalf("CleanupNow removed %d keys before any expired", n)
+	}
+	clk.Advance(2 * time.Second)
+	if n := s.CleanupNow(ctx); n != 4 {
+		t.Errorf("CleanupNow removed %d keys, want 4", n)
+	}
+	if size := s.Size(ctx); size != 6 {
+		t.Errorf("Size = %d after CleanupNow, want 6", size)
+	}
+	if len(expired) != 4 {
+		t.Errorf("expire hook fired for %v, want the 4 expired keys", expired)
+	}
+	if n := s.CleanupNow(ctx); n != 0 {
+		t.Errorf("second CleanupNow removed %d keys, want 0", n)
+	}
+}
+
+func TestMemoryUsageAtEmbstrBoundary(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	usage := make(map[int]int64)
+	for _, n := range []int{43, 44, 45} {
+		if err := s.Set(ctx, "k", strings.Repeat("x", n)); err != nil {
+			t.Fatal(err)
+		}
+		info, _ := s.Object(ctx, "k")
+		usage[n] = info.MemoryUsage
+	}
+	if usage[44]-usage[43] != 1 {
+		t.Errorf("embstr usage grew by %d for one byte, want 1", usage[44]-usage[43])
+	}
+	if usage[45]-usage[44] != 1+rawValueOverhead {
+		t.Errorf("raw usage grew by %d past 44 bytes, want %d", usage[45]-usage[44], 1+rawValueOverhead)
+	}
+}
+
+func TestKeysResultIsIndependent(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestStore(t, StoreOption{})
+	if err := s.SetMany(ctx, map[string]string{"a": "1", "b": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := s.Keys(ctx, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range keys {
+		keys[i] = "mutated"
+	}
+	if err := s.Set(ctx, "c", "3"); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := s.Keys(ctx, "*")
+	sort.Strings(again)
+	if !reflect.DeepEqual(again, []string{"a", "b", "c"}) {
+		t.Errorf("Keys after mutating an earlier result = %v", again)
+	}
+	if len(keys) != 2 {
+		t.Errorf("earlier result changed length to %d after a write", len(keys))
+	}
+
+	buf := make([]string, 0, 8)
+	into := s.KeysInto(ctx, "*", buf)
+	if len(into) != 3 || &into[:1][0] != &buf[:1][0] {
+		t.Errorf("KeysInto = %v, want 3 keys in the caller's buffer", into)
+	}
+}
+
+func BenchmarkKeys(b *testing.B) {
+	ctx := context.Background()
+	s := NewStore(StoreOption{})
+	s.SetMany(ctx, benchmarkItems(1000))
+	b.Run("Keys", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.Keys(ctx, "key:*")
+		}
+	})
+	b.Run("KeysInto", func(b *testing.B) {
+		b.ReportAllocs()
+		var buf []string
+		for i := 0; i < b.N; i++ {
+			buf = s.KeysInto(ctx, "key:*", buf)
+		}
+	})
+}
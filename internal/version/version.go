@@ -0,0 +1,5 @@
+package version
+
+// Version is the server version, set at build time with
+// -ldflags "-X github.com/alexsandroveiga/redis-like-golang/internal/version.Version=x.y.z".
+var Version = "0.0.0-dev"